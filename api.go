@@ -2,12 +2,12 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
 	"net/url"
-	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -20,9 +20,14 @@ import (
 	"github.com/Luzifer/go-latestver/internal/config"
 	"github.com/Luzifer/go-latestver/internal/database"
 	"github.com/Luzifer/go-latestver/internal/fetcher"
+	versioning "github.com/Luzifer/go-latestver/internal/version"
+	"github.com/Luzifer/go-latestver/internal/webhook"
 )
 
-const maxLogsPerPage = 100
+const (
+	defaultLogsPerPage = 25
+	maxLogsPerPage     = 100
+)
 
 type (
 	apiCatalogEntry struct {
@@ -31,6 +36,8 @@ type (
 	}
 )
 
+var errInvalidLogQuery = errors.New("invalid log query")
+
 func buildFullURL(u *url.URL, _ error) string {
 	return strings.Join([]string{
 		strings.TrimRight(cfg.BaseURL, "/"),
@@ -44,21 +51,7 @@ func catalogEntryToAPICatalogEntry(ce database.CatalogEntry) (apiCatalogEntry, e
 		return apiCatalogEntry{}, fmt.Errorf("fetching catalog meta: %w", err)
 	}
 
-	for _, l := range fetcher.Get(ce.Fetcher).Links(ce.FetcherConfig) {
-		var found bool
-		for _, el := range ce.Links {
-			if l.Name == el.Name {
-				found = true
-				break
-			}
-		}
-
-		if !found {
-			ce.Links = append(ce.Links, l)
-		}
-	}
-
-	return apiCatalogEntry{CatalogEntry: ce, CatalogMeta: *cm}, nil
+	return newAPICatalogEntry(ce, *cm), nil
 }
 
 func handleBadge(w http.ResponseWriter, r *http.Request) {
@@ -85,13 +78,98 @@ func handleBadge(w http.ResponseWriter, r *http.Request) {
 		color = "red"
 	}
 
-	svg := badge.Create(ce.Key(), cm.CurrentVersion, color)
+	// Versions out of range are expected when following a release line
+	// and therefore not shown as not applied
+	text := cm.CurrentVersion
+	if cm.BlockedVersion != "" && cm.BlockedReason != string(versioning.BlockReasonOutOfRange) && r.URL.Query().Get("blocked") != "false" {
+		text = fmt.Sprintf("%s (%s not applied)", cm.CurrentVersion, cm.BlockedVersion)
+	}
+
+	svg := badge.Create(ce.Key(), text, color)
 	w.Header().Add("Content-Type", "image/svg+xml")
 	if _, err = w.Write(svg); err != nil {
 		logrus.WithError(err).Error("writing SVG response")
 	}
 }
 
+func handleCatalogCheck(w http.ResponseWriter, r *http.Request) {
+	var (
+		vars      = mux.Vars(r)
+		name, tag = vars["name"], vars["tag"]
+	)
+
+	ce, err := configFile.CatalogEntryByTag(name, tag)
+	if errors.Is(err, config.ErrCatalogEntryNotFound) {
+		http.Error(w, "Not found", http.StatusNotFound)
+		return
+	}
+
+	// The check must not be aborted by the client disconnecting as the
+	// cancellation would be stored as error of the entry
+	release := checkLimits().acquire(ce.Fetcher)
+	defer release()
+
+	ctx, cancel := context.WithTimeout(appCtx, cfg.CheckTimeout)
+	defer cancel()
+
+	if err = forceCheck(ctx, &ce); err != nil {
+		logrus.WithError(err).Error("Unable to check catalog entry")
+		http.Error(w, "Unable to check catalog entry", http.StatusInternalServerError)
+		return
+	}
+
+	ae, err := catalogEntryToAPICatalogEntry(ce)
+	if err != nil {
+		logrus.WithError(err).Error("Unable to fetch catalog data")
+		http.Error(w, "Unable to fetch catalog data", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err = json.NewEncoder(w).Encode(ae); err != nil {
+		logrus.WithError(err).Error("Unable to encode catalog entry")
+		http.Error(w, "Unable to encode catalog meta", http.StatusInternalServerError)
+		return
+	}
+}
+
+func handleCatalogCheckAll(w http.ResponseWriter, _ *http.Request) {
+	if !forceCheckAll() {
+		http.Error(w, "Check of all entries already running", http.StatusConflict)
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func handleCatalogChecks(w http.ResponseWriter, r *http.Request) {
+	var (
+		vars      = mux.Vars(r)
+		name, tag = vars["name"], vars["tag"]
+		num, page = pagingFromRequest(r)
+	)
+
+	ce, err := configFile.CatalogEntryByTag(name, tag)
+	if errors.Is(err, config.ErrCatalogEntryNotFound) {
+		http.Error(w, "Not found", http.StatusNotFound)
+		return
+	}
+
+	checks, err := storage.Checks.ListForCatalogEntry(&ce, num, page)
+	if err != nil {
+		logrus.WithError(err).Error("Unable to fetch check attempts")
+		http.Error(w, "Unable to fetch check attempts", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err = json.NewEncoder(w).Encode(checks); err != nil {
+		logrus.WithError(err).Error("Unable to encode check attempts")
+		http.Error(w, "Unable to encode check attempts", http.StatusInternalServerError)
+		return
+	}
+}
+
 func handleCatalogGet(w http.ResponseWriter, r *http.Request) {
 	var (
 		vars      = mux.Vars(r)
@@ -142,26 +220,44 @@ func handleCatalogGetVersion(w http.ResponseWriter, r *http.Request) {
 	fmt.Fprint(w, cm.CurrentVersion) //nolint:errcheck // no need to log a single string
 }
 
-func handleCatalogList(w http.ResponseWriter, _ *http.Request) {
+func handleCatalogList(w http.ResponseWriter, r *http.Request) {
+	q, err := catalogListQueryFromRequest(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	metas, err := storage.Catalog.ListMeta()
+	if err != nil {
+		logrus.WithError(err).Error("Unable to fetch catalog data")
+		http.Error(w, "Unable to fetch catalog data", http.StatusInternalServerError)
+		return
+	}
+
+	metaByKey := make(map[string]database.CatalogMeta, len(metas))
+	for _, cm := range metas {
+		metaByKey[database.CatalogEntry{Name: cm.CatalogName, Tag: cm.CatalogTag}.Key()] = cm
+	}
+
 	out := make([]apiCatalogEntry, len(configFile.Catalog))
 
 	for i := range configFile.Catalog {
 		ce := configFile.Catalog[i]
 
-		ae, err := catalogEntryToAPICatalogEntry(ce)
-		if err != nil {
-			logrus.WithError(err).Error("Unable to fetch catalog data")
-			http.Error(w, "Unable to fetch catalog data", http.StatusInternalServerError)
-			return
+		cm, ok := metaByKey[ce.Key()]
+		if !ok {
+			// Entry was not checked yet
+			cm = database.CatalogMeta{CatalogName: ce.Name, CatalogTag: ce.Tag}
 		}
 
-		out[i] = ae
+		out[i] = newAPICatalogEntry(ce, cm)
 	}
 
-	sort.Slice(out, func(i, j int) bool { return out[i].Key() < out[j].Key() })
+	out, total := q.apply(out)
 
 	w.Header().Set("Content-Type", "application/json")
-	if err := json.NewEncoder(w).Encode(out); err != nil {
+	w.Header().Set("X-Total-Count", strconv.Itoa(total))
+	if err = json.NewEncoder(w).Encode(out); err != nil {
 		logrus.WithError(err).Error("Unable to encode catalog entry list")
 		http.Error(w, "Unable to encode catalog meta", http.StatusInternalServerError)
 		return
@@ -169,19 +265,27 @@ func handleCatalogList(w http.ResponseWriter, _ *http.Request) {
 }
 
 func handleLog(w http.ResponseWriter, r *http.Request) {
-	logs, err := prepareLogForRequest(r)
-	switch err {
-	case nil:
+	logs, q, err := prepareLogForRequest(r)
+	switch {
+	case err == nil:
 		// This is fine
 
-	case config.ErrCatalogEntryNotFound:
+	case errors.Is(err, config.ErrCatalogEntryNotFound):
 		http.Error(w, "Not found", http.StatusNotFound)
+		return
+
+	case errors.Is(err, errInvalidLogQuery), errors.Is(err, database.ErrUnknownCursor):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
 
 	default:
+		logrus.WithError(err).Error("Unable to fetch logs")
 		http.Error(w, "Unable to fetch logs", http.StatusInternalServerError)
 		return
 	}
 
+	setLogPaginationLinks(w, r, q, logs)
+
 	w.Header().Set("Content-Type", "application/json")
 	if err = json.NewEncoder(w).Encode(logs); err != nil {
 		logrus.WithError(err).Error("Unable to encode logs")
@@ -193,19 +297,27 @@ func handleLog(w http.ResponseWriter, r *http.Request) {
 func handleLogFeed(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 
-	logs, err := prepareLogForRequest(r)
-	switch err {
-	case nil:
+	logs, q, err := prepareLogForRequest(r)
+	switch {
+	case err == nil:
 		// This is fine
 
-	case config.ErrCatalogEntryNotFound:
+	case errors.Is(err, config.ErrCatalogEntryNotFound):
 		http.Error(w, "Not found", http.StatusNotFound)
+		return
+
+	case errors.Is(err, errInvalidLogQuery), errors.Is(err, database.ErrUnknownCursor):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
 
 	default:
+		logrus.WithError(err).Error("Unable to fetch logs")
 		http.Error(w, "Unable to fetch logs", http.StatusInternalServerError)
 		return
 	}
 
+	setLogPaginationLinks(w, r, q, logs)
+
 	feed := &feeds.Feed{
 		Description: "Generated by go-latestver: https://github.com/Luzifer/go-latestver",
 		Link:        &feeds.Link{Href: buildFullURL(router.Get("catalog").URL())},
@@ -217,58 +329,227 @@ func handleLogFeed(w http.ResponseWriter, r *http.Request) {
 		feed.Link.Href = buildFullURL(router.Get("catalog-entry").URL("name", vars["name"], "tag", vars["tag"]))
 	}
 
+	var groups []string
 	for _, le := range logs {
+		// Entries removed from the catalog have neither group nor labels
+		ce, _ := configFile.CatalogEntryByTag(le.CatalogName, le.CatalogTag)
+
+		desc := fmt.Sprintf("%s:%s updated to version %s from %s", le.CatalogName, le.CatalogTag, le.VersionTo, le.VersionFrom)
+		if len(ce.Labels) > 0 {
+			desc = fmt.Sprintf("%s (labels: %s)", desc, formatLabels(ce.Labels))
+		}
+
 		feed.Add(&feeds.Item{
 			Created:     le.Timestamp.UTC(),
-			Description: fmt.Sprintf("%s:%s updated to version %s from %s", le.CatalogName, le.CatalogTag, le.VersionTo, le.VersionFrom),
+			Description: desc,
 			Id:          fmt.Sprintf("%s:%s-%s", le.CatalogName, le.CatalogTag, le.Timestamp.UTC().Format(time.RFC3339)),
 			Link:        &feeds.Link{Href: buildFullURL(router.Get("catalog-entry").URL("name", le.CatalogName, "tag", le.CatalogTag))},
 			Title:       fmt.Sprintf("%s:%s %s", le.CatalogName, le.CatalogTag, le.VersionTo),
 		})
+		groups = append(groups, ce.Group)
+	}
+
+	// The generic feed items do not support categories so the group is
+	// set on the RSS items
+	rss := (&feeds.Rss{Feed: feed}).RssFeed()
+	for i, item := range rss.Items {
+		item.Category = groups[i]
 	}
 
 	w.Header().Set("Content-Type", "application/rss+xml; charset=utf-8")
-	if err = feed.WriteRss(w); err != nil {
+	if err = feeds.WriteXML(rss, w); err != nil {
 		logrus.WithError(err).Error("Unable to render RSS")
 		http.Error(w, "Unable to render RSS", http.StatusInternalServerError)
 		return
 	}
 }
 
-func prepareLogForRequest(r *http.Request) ([]database.LogEntry, error) {
+func handleWebhook(w http.ResponseWriter, r *http.Request) {
+	ev, err := webhook.Parse(mux.Vars(r)["provider"], r)
+	switch {
+	case err == nil:
+		// This is fine
+
+	case errors.Is(err, webhook.ErrUnknownProvider):
+		http.Error(w, "Unknown provider", http.StatusNotFound)
+		return
+
+	case errors.Is(err, webhook.ErrIgnoredEvent):
+		w.WriteHeader(http.StatusNoContent)
+		return
+
+	default:
+		logrus.WithError(err).Warn("Unable to parse webhook")
+		http.Error(w, "Invalid webhook payload", http.StatusBadRequest)
+		return
+	}
+
+	var (
+		entries []database.CatalogEntry
+		found   bool
+	)
+
+	for i := range configFile.Catalog {
+		ce := configFile.Catalog[i]
+		if ce.Webhook == nil || !strings.EqualFold(ce.Webhook.Repository, ev.Repository) {
+			continue
+		}
+
+		found = true
+		if !ev.Verify(ce.Webhook.Secret) {
+			logrus.WithFields(logrus.Fields{
+				"entry":    ce.Key(),
+				"provider": ev.Provider,
+			}).Warn("Received webhook with invalid signature")
+			continue
+		}
+
+		entries = append(entries, ce)
+	}
+
+	switch {
+	case !found:
+		http.Error(w, "No catalog entry for repository", http.StatusNotFound)
+		return
+
+	case len(entries) == 0:
+		http.Error(w, "Invalid signature", http.StatusUnauthorized)
+		return
+	}
+
+	backgroundChecks.Go(func() { runChecks(entries, forceCheck) })
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// logQueryFromRequest reads paging, cursors and time range for the
+// log from the request
+func logQueryFromRequest(r *http.Request) (q database.LogQuery, err error) {
+	params := r.URL.Query()
+	q.Num, q.Page = pagingFromRequest(r)
+
+	for param, target := range map[string]*uint64{"after": &q.After, "before": &q.Before} {
+		if v := params.Get(param); v != "" {
+			if *target, err = strconv.ParseUint(v, 10, 64); err != nil {
+				return q, fmt.Errorf("%w: parsing %s: %w", errInvalidLogQuery, param, err)
+			}
+		}
+	}
+
+	if q.After > 0 && q.Before > 0 {
+		return q, fmt.Errorf("%w: after and before are mutually exclusive", errInvalidLogQuery)
+	}
+
+	for param, target := range map[string]*time.Time{"since": &q.Since, "until": &q.Until} {
+		if v := params.Get(param); v != "" {
+			if *target, err = time.Parse(time.RFC3339, v); err != nil {
+				return q, fmt.Errorf("%w: parsing %s: %w", errInvalidLogQuery, param, err)
+			}
+		}
+	}
+
+	return q, nil
+}
+
+// newAPICatalogEntry combines the catalog entry with its meta and
+// adds the links provided by the fetcher
+func newAPICatalogEntry(ce database.CatalogEntry, cm database.CatalogMeta) apiCatalogEntry {
+	for _, l := range fetcher.Get(ce.Fetcher).Links(ce.FetcherConfig) {
+		var found bool
+		for _, el := range ce.Links {
+			if l.Name == el.Name {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			ce.Links = append(ce.Links, l)
+		}
+	}
+
+	return apiCatalogEntry{CatalogEntry: ce, CatalogMeta: cm}
+}
+
+// pagingFromRequest reads the number of entries per page and the
+// page to display from the request
+func pagingFromRequest(r *http.Request) (num, page int) {
+	num = defaultLogsPerPage
+
+	if v, err := strconv.Atoi(r.URL.Query().Get("num")); err == nil && v > 0 && v <= maxLogsPerPage {
+		num = v
+	}
+
+	if v, err := strconv.Atoi(r.URL.Query().Get("page")); err == nil && v >= 0 {
+		page = v
+	}
+
+	return num, page
+}
+
+func prepareLogForRequest(r *http.Request) ([]database.LogEntry, database.LogQuery, error) {
 	var (
 		vars      = mux.Vars(r)
 		name, tag = vars["name"], vars["tag"]
 
-		num, page = 25, 0
-
 		ce   database.CatalogEntry
 		err  error
 		logs []database.LogEntry
+		q    database.LogQuery
 	)
 
-	if v, err := strconv.Atoi(r.URL.Query().Get("num")); err == nil && v > 0 && v <= maxLogsPerPage {
-		num = v
+	if q, err = logQueryFromRequest(r); err != nil {
+		return nil, q, err
 	}
 
-	if v, err := strconv.Atoi(r.URL.Query().Get("page")); err == nil && v >= 0 {
-		page = v
+	filter, err := catalogFilterFromRequest(r)
+	if err != nil {
+		return nil, q, fmt.Errorf("%w: %w", errInvalidLogQuery, err)
+	}
+
+	if filter.active() {
+		q.CatalogEntries = filter.entries(configFile.Catalog)
 	}
 
-	if name == "" && tag == "" {
-		logs, err = storage.Logs.List(num, page)
-	} else {
+	if name != "" || tag != "" {
 		ce, err = configFile.CatalogEntryByTag(name, tag)
 		if errors.Is(err, config.ErrCatalogEntryNotFound) {
-			return nil, config.ErrCatalogEntryNotFound
+			return nil, q, config.ErrCatalogEntryNotFound
 		}
 
-		logs, err = storage.Logs.ListForCatalogEntry(&ce, num, page)
+		q.CatalogEntry = &ce
 	}
 
-	if err != nil {
-		return nil, fmt.Errorf("listing log entries: %w", err)
+	if logs, err = storage.Logs.Query(q); err != nil {
+		return nil, q, fmt.Errorf("listing log entries: %w", err)
 	}
 
-	return logs, nil
+	return logs, q, nil
+}
+
+// setLogPaginationLinks adds a Link header pointing to the next
+// (older) and previous (newer) page of the log using cursors
+func setLogPaginationLinks(w http.ResponseWriter, r *http.Request, q database.LogQuery, logs []database.LogEntry) {
+	pageURL := func(cursor string, id uint64) string {
+		params := r.URL.Query()
+		for _, p := range []string{"after", "before", "page"} {
+			params.Del(p)
+		}
+		params.Set(cursor, strconv.FormatUint(id, 10))
+
+		return buildFullURL(&url.URL{Path: r.URL.Path, RawQuery: params.Encode()}, nil)
+	}
+
+	var links []string
+
+	if len(logs) > 0 && (q.After > 0 || q.Before > 0 || q.Page > 0) {
+		links = append(links, fmt.Sprintf("<%s>; rel=\"prev\"", pageURL("after", logs[0].ID)))
+	}
+
+	if len(logs) > 0 && len(logs) == q.Num {
+		links = append(links, fmt.Sprintf("<%s>; rel=\"next\"", pageURL("before", logs[len(logs)-1].ID)))
+	}
+
+	if len(links) > 0 {
+		w.Header().Set("Link", strings.Join(links, ", "))
+	}
 }
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Luzifer/go-latestver/internal/config"
+	"github.com/Luzifer/go-latestver/internal/database"
+)
+
+func TestLogCatalogFilter(t *testing.T) {
+	var err error
+	if storage, err = database.NewClient("sqlite3", "file:apilogfilter?mode=memory&cache=shared"); err != nil {
+		t.Fatalf("unable to create database client: %s", err)
+	}
+	t.Cleanup(func() { _ = storage.Close() })
+
+	configFile = config.New()
+	configFile.Catalog = []database.CatalogEntry{
+		{Name: "alpine", Tag: "stable", Group: "base-images", Labels: map[string]string{"team": "platform"}},
+		{Name: "debian", Tag: "stable", Group: "base-images", Labels: map[string]string{"team": "dev"}},
+		{Name: "go", Tag: "latest", Group: "languages", Labels: map[string]string{"team": "platform"}},
+	}
+	router = newRouter()
+
+	rt := time.Now().UTC().Truncate(time.Hour)
+	for i, ce := range configFile.Catalog {
+		if err = storage.Logs.Add(&database.LogEntry{
+			CatalogName: ce.Name,
+			CatalogTag:  ce.Tag,
+			Timestamp:   rt.Add(time.Duration(i) * time.Minute),
+			VersionTo:   "1.0.0",
+		}); err != nil {
+			t.Fatalf("adding log entry: %s", err)
+		}
+	}
+
+	for _, tc := range []struct {
+		path   string
+		status int
+		expect []string
+	}{
+		{"/v1/log", http.StatusOK, []string{"go:latest", "debian:stable", "alpine:stable"}},
+		{"/v1/log?group=base-images", http.StatusOK, []string{"debian:stable", "alpine:stable"}},
+		{"/v1/log?label=team=platform", http.StatusOK, []string{"go:latest", "alpine:stable"}},
+		{"/v1/log?group=base-images&label=team=platform", http.StatusOK, []string{"alpine:stable"}},
+		{"/v1/log?group=tools", http.StatusOK, []string{}},
+		{"/v1/log?label=team=", http.StatusOK, []string{}},
+		{"/v1/catalog/go/latest/log?group=base-images", http.StatusOK, []string{}},
+		{"/v1/log?label==platform", http.StatusBadRequest, nil},
+		{"/log.rss?label=team=platform", http.StatusOK, []string{"go:latest", "alpine:stable"}},
+		{"/alpine/stable/log.rss?group=base-images", http.StatusOK, []string{"alpine:stable"}},
+		{"/log.rss?label=", http.StatusBadRequest, nil},
+	} {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tc.path, nil))
+
+		if rec.Code != tc.status {
+			t.Errorf("%s: expected status %d, got %d", tc.path, tc.status, rec.Code)
+			continue
+		}
+
+		if tc.status != http.StatusOK {
+			continue
+		}
+
+		keys := []string{}
+		if strings.Contains(tc.path, ".rss") {
+			// Feed items are titled "name:tag version"
+			for _, line := range strings.Split(rec.Body.String(), "<title>")[2:] {
+				keys = append(keys, strings.Fields(line)[0])
+			}
+		} else {
+			var logs []database.LogEntry
+			if err = json.NewDecoder(rec.Body).Decode(&logs); err != nil {
+				t.Fatalf("%s: decoding log: %s", tc.path, err)
+			}
+
+			for _, le := range logs {
+				keys = append(keys, le.CatalogName+":"+le.CatalogTag)
+			}
+		}
+
+		if !slices.Equal(keys, tc.expect) {
+			t.Errorf("%s: expected entries %v, got %v", tc.path, tc.expect, keys)
+		}
+	}
+}
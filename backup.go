@@ -0,0 +1,146 @@
+package main
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/Luzifer/go-latestver/internal/database"
+)
+
+// exportDatabase writes a backup of the database into the file given
+// as first argument or to stdout if no file (or "-") is given
+func exportDatabase(args []string) (err error) {
+	var w io.Writer = os.Stdout
+
+	if len(args) > 0 && args[0] != "-" {
+		f, err := os.Create(args[0])
+		if err != nil {
+			return fmt.Errorf("creating backup file: %w", err)
+		}
+		defer func() {
+			if cerr := f.Close(); cerr != nil && err == nil {
+				err = fmt.Errorf("closing backup file: %w", cerr)
+			}
+		}()
+
+		w = f
+	}
+
+	res, err := storage.Export(w)
+	if err != nil {
+		return fmt.Errorf("exporting database: %w", err)
+	}
+
+	log.WithFields(log.Fields{
+		"catalog_metas": res.CatalogMetas,
+		"log_entries":   res.LogEntries,
+	}).Info("Exported database")
+
+	return nil
+}
+
+func handleAdminExport(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(
+		"attachment; filename=\"latestver-%s.ndjson\"",
+		time.Now().UTC().Format("20060102-150405"),
+	))
+
+	if _, err := storage.Export(w); err != nil {
+		// Headers are already sent, we can only abort the response
+		log.WithError(err).Error("Unable to export database")
+		panic(http.ErrAbortHandler)
+	}
+}
+
+func handleAdminImport(w http.ResponseWriter, r *http.Request) {
+	// The import is executed in one transaction which must not be held
+	// open by an endless body
+	res, err := storage.Import(http.MaxBytesReader(w, r.Body, cfg.AdminImportMaxSize))
+
+	var maxBytesErr *http.MaxBytesError
+	switch {
+	case err == nil:
+		// This is fine
+
+	case errors.As(err, &maxBytesErr):
+		http.Error(w, fmt.Sprintf("Backup exceeds %d bytes", maxBytesErr.Limit), http.StatusRequestEntityTooLarge)
+		return
+
+	case errors.Is(err, database.ErrInvalidBackup):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+
+	default:
+		log.WithError(err).Error("Unable to import backup")
+		http.Error(w, "Unable to import backup", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err = json.NewEncoder(w).Encode(map[string]int64{
+		"catalog_metas": res.CatalogMetas,
+		"log_entries":   res.LogEntries,
+		"skipped":       res.Skipped,
+	}); err != nil {
+		log.WithError(err).Error("Unable to encode import result")
+		return
+	}
+}
+
+// importDatabase merges the backup read from the file given as first
+// argument or from stdin if no file (or "-") is given into the database
+func importDatabase(args []string) error {
+	var r io.Reader = os.Stdin
+
+	if len(args) > 0 && args[0] != "-" {
+		f, err := os.Open(args[0])
+		if err != nil {
+			return fmt.Errorf("opening backup file: %w", err)
+		}
+		defer f.Close() //nolint:errcheck // file is only read
+
+		r = f
+	}
+
+	res, err := storage.Import(r)
+	if err != nil {
+		return fmt.Errorf("importing backup: %w", err)
+	}
+
+	log.WithFields(log.Fields{
+		"catalog_metas": res.CatalogMetas,
+		"log_entries":   res.LogEntries,
+		"skipped":       res.Skipped,
+	}).Info("Imported backup")
+
+	return nil
+}
+
+// requireAdminToken protects the admin endpoints with the token given
+// in --admin-token, the endpoints are disabled if no token is set
+func requireAdminToken(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if cfg.AdminToken == "" {
+			http.NotFound(w, r)
+			return
+		}
+
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(cfg.AdminToken)) != 1 {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		next(w, r)
+	}
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Luzifer/go-latestver/internal/database"
+)
+
+func TestHandleAdminImport(t *testing.T) {
+	var err error
+	if storage, err = database.NewClient("sqlite3", "file:adminimport?mode=memory&cache=shared"); err != nil {
+		t.Fatalf("unable to create database client: %s", err)
+	}
+	t.Cleanup(func() { _ = storage.Close() })
+
+	var (
+		header = `{"type":"header","version":1}` + "\n"
+		entry  = `{"type":"log_entry","log_entry":{"catalog_name":"app","catalog_tag":"latest","timestamp":"2024-01-01T00:00:00Z","version_to":"1.0.0"}}` + "\n"
+	)
+
+	for _, tc := range []struct {
+		name    string
+		body    string
+		maxSize int64
+		status  int
+	}{
+		{"valid backup", header + entry, 1024, http.StatusOK},
+		{"invalid backup", "{}\n", 1024, http.StatusBadRequest},
+		{"too large backup", header + strings.Repeat(entry, 10), 1024, http.StatusRequestEntityTooLarge},
+	} {
+		cfg.AdminImportMaxSize = tc.maxSize
+
+		rec := httptest.NewRecorder()
+		handleAdminImport(rec, httptest.NewRequest(http.MethodPost, "/v1/admin/import", strings.NewReader(tc.body)))
+
+		if rec.Code != tc.status {
+			t.Errorf("%s: expected status %d, got %d: %s", tc.name, tc.status, rec.Code, rec.Body.String())
+		}
+	}
+}
@@ -0,0 +1,221 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Luzifer/go-latestver/internal/database"
+)
+
+const (
+	catalogSortKey         = "key"
+	catalogSortLastChecked = "last_checked"
+	catalogSortVersionTime = "version_time"
+
+	catalogStatusError        = "error"
+	catalogStatusNeverChecked = "never-checked"
+	catalogStatusOK           = "ok"
+)
+
+type (
+	// catalogFilter selects catalog entries by their group and labels
+	catalogFilter struct {
+		Group string
+		// Labels contains label selectors in the form key=value or key
+		// (label must be set), all of them must match
+		Labels []string
+	}
+
+	// catalogListQuery filters, sorts and paginates the catalog list
+	catalogListQuery struct {
+		Fetcher      string
+		Filter       catalogFilter
+		Limit        int
+		Offset       int
+		Search       string
+		Sort         string
+		Status       string
+		UpdatedSince time.Time
+	}
+)
+
+var errInvalidCatalogQuery = errors.New("invalid catalog query")
+
+func catalogFilterFromRequest(r *http.Request) (f catalogFilter, err error) {
+	params := r.URL.Query()
+
+	f.Group = params.Get("group")
+
+	for _, sel := range params["label"] {
+		if key, _, _ := strings.Cut(sel, "="); key == "" {
+			return f, fmt.Errorf("label selector %q has no key", sel)
+		}
+		f.Labels = append(f.Labels, sel)
+	}
+
+	return f, nil
+}
+
+func catalogListQueryFromRequest(r *http.Request) (q catalogListQuery, err error) {
+	params := r.URL.Query()
+
+	if q.Filter, err = catalogFilterFromRequest(r); err != nil {
+		return q, fmt.Errorf("%w: %w", errInvalidCatalogQuery, err)
+	}
+
+	q.Fetcher = params.Get("fetcher")
+	q.Search = strings.ToLower(params.Get("q"))
+
+	switch q.Sort = params.Get("sort"); q.Sort {
+	case "":
+		q.Sort = catalogSortKey
+	case catalogSortKey, catalogSortLastChecked, catalogSortVersionTime:
+		// Valid sort order
+	default:
+		return q, fmt.Errorf("%w: unknown sort order %q", errInvalidCatalogQuery, q.Sort)
+	}
+
+	switch q.Status = params.Get("status"); q.Status {
+	case "", catalogStatusError, catalogStatusNeverChecked, catalogStatusOK:
+		// Valid status
+	default:
+		return q, fmt.Errorf("%w: unknown status %q", errInvalidCatalogQuery, q.Status)
+	}
+
+	for param, target := range map[string]*int{"limit": &q.Limit, "offset": &q.Offset} {
+		if v := params.Get(param); v != "" {
+			if *target, err = strconv.Atoi(v); err != nil || *target < 0 {
+				return q, fmt.Errorf("%w: %s must be a non-negative number", errInvalidCatalogQuery, param)
+			}
+		}
+	}
+
+	if v := params.Get("updated_since"); v != "" {
+		if q.UpdatedSince, err = time.Parse(time.RFC3339, v); err != nil {
+			return q, fmt.Errorf("%w: parsing updated_since: %w", errInvalidCatalogQuery, err)
+		}
+	}
+
+	return q, nil
+}
+
+// active reports whether the filter restricts the catalog entries
+func (f catalogFilter) active() bool {
+	return f.Group != "" || len(f.Labels) > 0
+}
+
+// entries returns the catalog entries matching the filter, the
+// returned list is never nil
+func (f catalogFilter) entries(catalog []database.CatalogEntry) []database.CatalogEntry {
+	out := []database.CatalogEntry{}
+	for _, ce := range catalog {
+		if f.matches(ce) {
+			out = append(out, ce)
+		}
+	}
+
+	return out
+}
+
+func (f catalogFilter) matches(ce database.CatalogEntry) bool {
+	if f.Group != "" && ce.Group != f.Group {
+		return false
+	}
+
+	for _, sel := range f.Labels {
+		key, value, hasValue := strings.Cut(sel, "=")
+		if v, ok := ce.Labels[key]; !ok || (hasValue && v != value) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// apply filters and sorts the entries and returns the requested page
+// together with the total number of matching entries
+func (q catalogListQuery) apply(entries []apiCatalogEntry) ([]apiCatalogEntry, int) {
+	out := slices.DeleteFunc(slices.Clone(entries), func(ae apiCatalogEntry) bool { return !q.matches(ae) })
+
+	slices.SortFunc(out, func(a, b apiCatalogEntry) int {
+		var c int
+		switch q.Sort {
+		case catalogSortLastChecked:
+			c = compareTimeDesc(a.LastChecked, b.LastChecked)
+		case catalogSortVersionTime:
+			c = compareTimeDesc(a.VersionTime, b.VersionTime)
+		}
+
+		if c != 0 {
+			return c
+		}
+		return strings.Compare(a.Key(), b.Key())
+	})
+
+	total := len(out)
+	out = out[min(q.Offset, total):]
+	if q.Limit > 0 && q.Limit < len(out) {
+		out = out[:q.Limit]
+	}
+
+	return out, total
+}
+
+func (q catalogListQuery) matches(ae apiCatalogEntry) bool {
+	if q.Search != "" && !strings.Contains(strings.ToLower(ae.Name), q.Search) && !strings.Contains(strings.ToLower(ae.Tag), q.Search) {
+		return false
+	}
+
+	if !q.Filter.matches(ae.CatalogEntry) {
+		return false
+	}
+
+	if q.Fetcher != "" && ae.Fetcher != q.Fetcher {
+		return false
+	}
+
+	if !q.UpdatedSince.IsZero() && (ae.VersionTime == nil || ae.VersionTime.Before(q.UpdatedSince)) {
+		return false
+	}
+
+	switch q.Status {
+	case catalogStatusError:
+		return ae.Error != ""
+	case catalogStatusNeverChecked:
+		return ae.LastChecked == nil
+	case catalogStatusOK:
+		return ae.LastChecked != nil && ae.Error == ""
+	}
+
+	return true
+}
+
+// compareTimeDesc orders the more recent time first and unset times last
+func compareTimeDesc(a, b *time.Time) int {
+	switch {
+	case a == nil && b == nil:
+		return 0
+	case a == nil:
+		return 1
+	case b == nil:
+		return -1
+	}
+
+	return b.Compare(*a)
+}
+
+// formatLabels returns the labels as sorted key=value list
+func formatLabels(labels map[string]string) string {
+	out := make([]string, 0, len(labels))
+	for k, v := range labels {
+		out = append(out, k+"="+v)
+	}
+	slices.Sort(out)
+
+	return strings.Join(out, ", ")
+}
@@ -0,0 +1,325 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/Luzifer/go-latestver/internal/database"
+)
+
+func TestCatalogListQueryFromRequest(t *testing.T) {
+	since := time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC)
+
+	for _, tc := range []struct {
+		name   string
+		query  string
+		expect catalogListQuery
+		err    bool
+	}{
+		{name: "defaults", expect: catalogListQuery{Sort: catalogSortKey}},
+		{
+			name:  "all parameters",
+			query: "fetcher=github_release&limit=10&offset=20&q=AlPine&sort=version_time&status=error&updated_since=2024-01-15T12:00:00Z",
+			expect: catalogListQuery{
+				Fetcher:      "github_release",
+				Limit:        10,
+				Offset:       20,
+				Search:       "alpine",
+				Sort:         catalogSortVersionTime,
+				Status:       catalogStatusError,
+				UpdatedSince: since,
+			},
+		},
+		{name: "unknown sort order", query: "sort=name", err: true},
+		{name: "unknown status", query: "status=broken", err: true},
+		{name: "negative limit", query: "limit=-1", err: true},
+		{name: "non-numeric offset", query: "offset=ten", err: true},
+		{name: "invalid updated_since", query: "updated_since=2024-01-15", err: true},
+		{name: "label without key", query: "label==value", err: true},
+	} {
+		q, err := catalogListQueryFromRequest(httptest.NewRequest(http.MethodGet, "/v1/catalog?"+tc.query, nil))
+		if tc.err {
+			if !errors.Is(err, errInvalidCatalogQuery) {
+				t.Errorf("%s: expected invalid query error, got %v", tc.name, err)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Fatalf("%s: parsing query: %s", tc.name, err)
+		}
+
+		if q.Fetcher != tc.expect.Fetcher || q.Limit != tc.expect.Limit || q.Offset != tc.expect.Offset ||
+			q.Search != tc.expect.Search || q.Sort != tc.expect.Sort || q.Status != tc.expect.Status ||
+			!q.UpdatedSince.Equal(tc.expect.UpdatedSince) {
+			t.Errorf("%s: expected %+v, got %+v", tc.name, tc.expect, q)
+		}
+	}
+}
+
+func TestCatalogListQueryApply(t *testing.T) {
+	var (
+		now   = time.Now().UTC()
+		older = now.Add(-time.Hour)
+	)
+
+	entries := []apiCatalogEntry{
+		{
+			CatalogEntry: database.CatalogEntry{Name: "alpine", Tag: "stable", Fetcher: "html", Group: "base-images", Labels: map[string]string{"channel": "stable"}},
+			CatalogMeta:  database.CatalogMeta{LastChecked: &older, VersionTime: &now},
+		},
+		{
+			CatalogEntry: database.CatalogEntry{Name: "go", Tag: "latest", Fetcher: "github_release"},
+			CatalogMeta:  database.CatalogMeta{LastChecked: &now, VersionTime: &older, Error: "fetch failed"},
+		},
+		{
+			CatalogEntry: database.CatalogEntry{Name: "node", Tag: "lts", Fetcher: "github_release"},
+		},
+		{
+			CatalogEntry: database.CatalogEntry{Name: "alpine", Tag: "edge", Fetcher: "html", Group: "base-images", Labels: map[string]string{"channel": "edge"}},
+			CatalogMeta:  database.CatalogMeta{LastChecked: &now},
+		},
+	}
+
+	for _, tc := range []struct {
+		name   string
+		query  catalogListQuery
+		expect []string
+		total  int
+	}{
+		{
+			name:   "sorted by key",
+			query:  catalogListQuery{Sort: catalogSortKey},
+			expect: []string{"alpine:edge", "alpine:stable", "go:latest", "node:lts"},
+			total:  4,
+		},
+		{
+			name:   "sorted by last check with unchecked entries last",
+			query:  catalogListQuery{Sort: catalogSortLastChecked},
+			expect: []string{"alpine:edge", "go:latest", "alpine:stable", "node:lts"},
+			total:  4,
+		},
+		{
+			name:   "sorted by version time with unset times last",
+			query:  catalogListQuery{Sort: catalogSortVersionTime},
+			expect: []string{"alpine:stable", "go:latest", "alpine:edge", "node:lts"},
+			total:  4,
+		},
+		{
+			name:   "offset and limit",
+			query:  catalogListQuery{Sort: catalogSortKey, Offset: 1, Limit: 2},
+			expect: []string{"alpine:stable", "go:latest"},
+			total:  4,
+		},
+		{
+			name:   "limit exceeding entries",
+			query:  catalogListQuery{Sort: catalogSortKey, Offset: 3, Limit: 10},
+			expect: []string{"node:lts"},
+			total:  4,
+		},
+		{
+			name:   "offset past the end",
+			query:  catalogListQuery{Sort: catalogSortKey, Offset: 10},
+			expect: []string{},
+			total:  4,
+		},
+		{
+			name:   "search in name and tag",
+			query:  catalogListQuery{Sort: catalogSortKey, Search: "l"},
+			expect: []string{"alpine:edge", "alpine:stable", "go:latest", "node:lts"},
+			total:  4,
+		},
+		{
+			name:   "search in tag",
+			query:  catalogListQuery{Sort: catalogSortKey, Search: "lts"},
+			expect: []string{"node:lts"},
+			total:  1,
+		},
+		{
+			name:   "fetcher",
+			query:  catalogListQuery{Sort: catalogSortKey, Fetcher: "github_release", Limit: 1},
+			expect: []string{"go:latest"},
+			total:  2,
+		},
+		{
+			name:   "status error",
+			query:  catalogListQuery{Sort: catalogSortKey, Status: catalogStatusError},
+			expect: []string{"go:latest"},
+			total:  1,
+		},
+		{
+			name:   "status never checked",
+			query:  catalogListQuery{Sort: catalogSortKey, Status: catalogStatusNeverChecked},
+			expect: []string{"node:lts"},
+			total:  1,
+		},
+		{
+			name:   "status ok",
+			query:  catalogListQuery{Sort: catalogSortKey, Status: catalogStatusOK},
+			expect: []string{"alpine:edge", "alpine:stable"},
+			total:  2,
+		},
+		{
+			name:   "group filter",
+			query:  catalogListQuery{Sort: catalogSortKey, Filter: catalogFilter{Group: "base-images"}},
+			expect: []string{"alpine:edge", "alpine:stable"},
+			total:  2,
+		},
+		{
+			name:   "label filter",
+			query:  catalogListQuery{Sort: catalogSortKey, Filter: catalogFilter{Labels: []string{"channel=stable"}}},
+			expect: []string{"alpine:stable"},
+			total:  1,
+		},
+		{
+			name:   "updated since excludes unset version times",
+			query:  catalogListQuery{Sort: catalogSortKey, UpdatedSince: older.Add(time.Minute)},
+			expect: []string{"alpine:stable"},
+			total:  1,
+		},
+	} {
+		page, total := tc.query.apply(entries)
+
+		keys := []string{}
+		for _, ae := range page {
+			keys = append(keys, ae.Key())
+		}
+
+		if !slices.Equal(keys, tc.expect) {
+			t.Errorf("%s: expected entries %v, got %v", tc.name, tc.expect, keys)
+		}
+
+		if total != tc.total {
+			t.Errorf("%s: expected total %d, got %d", tc.name, tc.total, total)
+		}
+	}
+
+	if entries[0].Key() != "alpine:stable" {
+		t.Error("apply must not modify the given entries")
+	}
+}
+
+func TestCompareTimeDesc(t *testing.T) {
+	var (
+		now   = time.Now()
+		older = now.Add(-time.Hour)
+	)
+
+	for _, tc := range []struct {
+		name   string
+		a, b   *time.Time
+		expect int
+	}{
+		{"both unset", nil, nil, 0},
+		{"first unset", nil, &now, 1},
+		{"second unset", &now, nil, -1},
+		{"first more recent", &now, &older, -1},
+		{"second more recent", &older, &now, 1},
+		{"equal", &now, &now, 0},
+	} {
+		if c := compareTimeDesc(tc.a, tc.b); c != tc.expect {
+			t.Errorf("%s: expected %d, got %d", tc.name, tc.expect, c)
+		}
+	}
+}
+
+func TestCatalogFilterFromRequest(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		query  string
+		expect catalogFilter
+		err    bool
+	}{
+		{name: "no filter", expect: catalogFilter{}},
+		{name: "group", query: "group=base-images", expect: catalogFilter{Group: "base-images"}},
+		{name: "label key", query: "label=team", expect: catalogFilter{Labels: []string{"team"}}},
+		{name: "label key and value", query: "label=team=platform", expect: catalogFilter{Labels: []string{"team=platform"}}},
+		{name: "label with empty value", query: "label=team=", expect: catalogFilter{Labels: []string{"team="}}},
+		{name: "value containing equal sign", query: "label=expr=a=b", expect: catalogFilter{Labels: []string{"expr=a=b"}}},
+		{
+			name:   "group and multiple labels",
+			query:  "group=base-images&label=team=platform&label=tier",
+			expect: catalogFilter{Group: "base-images", Labels: []string{"team=platform", "tier"}},
+		},
+		{name: "empty label", query: "label=", err: true},
+		{name: "empty key with value", query: "label==platform", err: true},
+		{name: "empty key among valid labels", query: "label=team&label==platform", err: true},
+	} {
+		f, err := catalogFilterFromRequest(httptest.NewRequest(http.MethodGet, "/v1/catalog?"+tc.query, nil))
+		if tc.err {
+			if err == nil {
+				t.Errorf("%s: expected error", tc.name)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Fatalf("%s: parsing filter: %s", tc.name, err)
+		}
+
+		if f.Group != tc.expect.Group || !slices.Equal(f.Labels, tc.expect.Labels) {
+			t.Errorf("%s: expected %+v, got %+v", tc.name, tc.expect, f)
+		}
+
+		if f.active() != (tc.expect.Group != "" || len(tc.expect.Labels) > 0) {
+			t.Errorf("%s: unexpected active state %v", tc.name, f.active())
+		}
+	}
+}
+
+func TestCatalogFilterEntries(t *testing.T) {
+	catalog := []database.CatalogEntry{
+		{Name: "alpine", Tag: "stable", Group: "base-images", Labels: map[string]string{"team": "platform", "tier": "1"}},
+		{Name: "debian", Tag: "stable", Group: "base-images", Labels: map[string]string{"team": ""}},
+		{Name: "go", Tag: "latest", Group: "languages", Labels: map[string]string{"team": "dev"}},
+		{Name: "node", Tag: "lts"},
+	}
+
+	for _, tc := range []struct {
+		name   string
+		filter catalogFilter
+		expect []string
+	}{
+		{"no filter", catalogFilter{}, []string{"alpine:stable", "debian:stable", "go:latest", "node:lts"}},
+		{"group", catalogFilter{Group: "base-images"}, []string{"alpine:stable", "debian:stable"}},
+		{"unknown group", catalogFilter{Group: "tools"}, []string{}},
+		{"label set", catalogFilter{Labels: []string{"team"}}, []string{"alpine:stable", "debian:stable", "go:latest"}},
+		{"label value", catalogFilter{Labels: []string{"team=platform"}}, []string{"alpine:stable"}},
+		{"label with empty value", catalogFilter{Labels: []string{"team="}}, []string{"debian:stable"}},
+		{"all labels must match", catalogFilter{Labels: []string{"team=platform", "tier=2"}}, []string{}},
+		{"group and label", catalogFilter{Group: "base-images", Labels: []string{"tier"}}, []string{"alpine:stable"}},
+		{"group and label of other group", catalogFilter{Group: "languages", Labels: []string{"team=platform"}}, []string{}},
+	} {
+		keys := []string{}
+		for _, ce := range tc.filter.entries(catalog) {
+			keys = append(keys, ce.Key())
+		}
+
+		if !slices.Equal(keys, tc.expect) {
+			t.Errorf("%s: expected entries %v, got %v", tc.name, tc.expect, keys)
+		}
+	}
+
+	if entries := (catalogFilter{Group: "tools"}).entries(catalog); entries == nil {
+		t.Error("entries must not return nil as it would disable the log filter")
+	}
+}
+
+func TestFormatLabels(t *testing.T) {
+	for _, tc := range []struct {
+		labels map[string]string
+		expect string
+	}{
+		{nil, ""},
+		{map[string]string{"team": "platform"}, "team=platform"},
+		{map[string]string{"tier": "1", "team": "platform", "empty": ""}, "empty=, team=platform, tier=1"},
+	} {
+		if got := formatLabels(tc.labels); got != tc.expect {
+			t.Errorf("expected %q for %v, got %q", tc.expect, tc.labels, got)
+		}
+	}
+}
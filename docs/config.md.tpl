@@ -13,14 +13,30 @@ catalog:
       url: https://alpinelinux.org/downloads/
       xpath: '//div[@class="l-box"]/p/strong'
 
+    check_interval: 6h
+    # check_schedule: '0 4 * * *'
+
+    webhook:
+      repository: alpinelinux/aports
+      secret: 'my-webhook-secret'
+
     links:
       - icon_class: 'fas fa-globe'
         name: 'Website'
         url: 'https://alpinelinux.org'
 
+    group: base-images
+    labels:
+      team: platform
+
+    transform:
+      - type: trim_prefix
+        value: v
+
     version_constraint:
       allow_downgrade: false
       allow_prerelease: false
+      range: '>=3.0'
       type: semver
 
 check_interval: 1h
@@ -34,7 +50,62 @@ Additionally you will configure a `fetcher` with its corresponding `fetcher_conf
 
 You can provide your own `links` for each catalog entry which will be added to or override the links returned from the fetcher. If you provide the same `name` as the fetcher uses the link of the fetcher will be overridden. The `icon_class` should consist of `fas` or `fab` and an icon (for example `fa-globe`). You can use all **solid** (`fas`) or **breand** (`fab`) icons within [Font Awesome v5 Free](https://fontawesome.com/v5.15/icons?d=gallery&s=brands,solid&m=free).
 
-For the `version_constraint`s you must specify a `type` to parse the version returned by the fetcher (currently `semver` and `numeric_dot` (`104.0.5112.79`) are supported) and then can allow downgrades and pre-releases in the version. If no constraint is present, versions are neither parsed nor checked for downgrade / pre-release. If only the `type` is specified, downgrades and pre-releases are forbidden.
+To organize the catalog each entry can be assigned to a `group` and carry free-form `labels` (keys must not be empty or contain `=`). Both are exposed in the API and in the RSS feeds (the group as category of the items) and can be used to filter the catalog list, the update log and the feeds: `group=base-images` selects the entries of the group, `label=team=platform` the entries having the label `team` set to `platform` and `label=team` the entries having the label `team` set at all. Multiple `label` parameters must all match (for example `/log.rss?label=team=platform&label=tier=1`).
+
+By default all catalog entries are checked once per `check_interval` configured at the top level of the config file (falling back to the `--check-distribution` flag if not set). The checks are spread over the interval using a static offset derived from the name and tag of the entry. To check an entry more or less often set its own `check_interval` (for example `15m` for a nightly channel or `168h` for a yearly release) or define a `check_schedule` in [standard cron syntax](https://pkg.go.dev/github.com/robfig/cron/v3) (`0 4 * * *`, `@daily`, …) to check at fixed times. Only one of both can be set for each entry. Entries failing repeatedly are checked less often: starting with the second consecutive failure the delay between checks is doubled for every failure up to the `--backoff-max` flag. The first successful check resets the entry to its regular interval. The number of consecutive failures and the time of the first failure are exposed as `consecutive_failures` and `first_failure` in the API.
+
+To learn about new versions without waiting for the next check you can configure a `webhook` for the entry: when a webhook for the given `repository` is received and its signature matches the `secret`, all matching entries are checked immediately. The webhook needs to be sent to `/v1/webhook/<provider>`:
+
+| Provider | Events | Repository | Secret |
+| -------- | ------ | ---------- | ------ |
+| `dockerhub` | Image push | `repo_name` (`library/alpine`) | Docker Hub does not sign webhooks, append `?secret=<secret>` to the URL (the parameter is removed before the request is written to the access log) |
+| `gitea` | `create` (tags), `push` (tags), `release` (published) | `full_name` (`owner/repo`) | HMAC-SHA256 signature in `X-Gitea-Signature` |
+| `github` | `create` (tags), `push` (tags), `release` (published) | `full_name` (`owner/repo`) | HMAC-SHA256 signature in `X-Hub-Signature-256` |
+| `gitlab` | Tag push, release | `path_with_namespace` (`group/project`) | GitLab does not sign webhooks, the secret is sent as `X-Gitlab-Token` |
+
+The version returned by the fetcher can be post-processed using an ordered list of `transform` steps before it is compared and stored. If no `transform` is given a leading `v` is stripped from the version (same as specifying a single `trim_prefix` step with value `v`), to keep the version exactly as fetched specify `transform: []`. Available step types:
+
+| Type | Parameters | Description |
+| ---- | ---------- | ----------- |
+| `lowercase` | | Converts the version to lowercase |
+| `regex_replace` | `match`, `replace` | Replaces all matches of the regular expression `match` with `replace` (`$1` references submatches) |
+| `template` | `template`, `match` | Renders the Go template `template` with `.Version` being the current version and `.Match` being the submatches of the optional regular expression `match` (the step fails if `match` is given and does not match) |
+| `trim_prefix` | `value` | Removes `value` from the start of the version |
+| `trim_suffix` | `value` | Removes `value` from the end of the version |
+| `underscore_to_dot` | | Replaces all `_` with `.` (`1_2_3` becomes `1.2.3`) |
+
+For example a tag like `jdk-21.0.2+13` can be converted into `21.0.2.13` using a `template` step with `match: '^jdk-([0-9.]+)\+([0-9]+)$'` and {% raw %}`template: '{{ index .Match 1 }}.{{ index .Match 2 }}'`{% endraw %}.
+
+For the `version_constraint`s you must specify a `type` to parse the version returned by the fetcher and then can allow downgrades and pre-releases in the version. If no constraint is present, versions are neither parsed nor checked for downgrade / pre-release. If only the `type` is specified, downgrades and pre-releases are forbidden.
+
+| Type | Example | Pre-Releases |
+| ---- | ------- | ------------ |
+| `calver` | `2024.01.15`, `24.04`, `2024.1.0rc1` | Modifier like `rc1`, `beta`, `dev0` after the date |
+| `debian` | `1:2.34-0ubuntu3.2` (`epoch:upstream-revision`) | Tilde in upstream version (`1.0~rc1-1`) |
+| `maven` | `1.0.0`, `32.1.3-jre`, `1.0-SNAPSHOT` (Maven `ComparableVersion`) | Qualifiers `alpha`, `beta`, `milestone`, `rc` / `cr`, `snapshot` |
+| `numeric_dot` | `104.0.5112.79` | Not supported, use `semver_loose` for numeric versions with pre-release markers |
+| `pep440` | `1!2.0.post1`, `24.0`, `1.0rc1` (Python packages) | Pre- (`a`, `b`, `rc`) and dev-releases (`.dev0`) |
+| `rpm` | `1:1.1.1k-7.el8` (`epoch:version-release`) | Tilde (`1.0~rc1`) or marker in release (`1.0-0.1.beta2`) |
+| `semver` | `1.2.3`, `1.2.3-rc.1` ([Semantic Versioning](https://semver.org/)) | Pre-release part (`-rc.1`) |
+| `semver_loose` | `v1.2`, `1.02.3`, `1.2.3.4`, `2.0.0rc1` (anything looking like a version) | Markers like `-rc1`, `beta`, `.dev0`, `alpha` |
+
+To follow a release line (for example `kubernetes:1.28` or `postgres:15`) a `range` can be added to the `version_constraint`. Versions outside the range are not applied and not reported as errors. A range consists of clauses which all must match, separated by whitespace or comma, and multiple alternatives can be combined using `||`:
+
+| Clause | Meaning |
+| ------ | ------- |
+| `>=1.28.0`, `>1.28.0`, `<=1.28.0`, `<1.29.0`, `=1.28.0`, `!=1.28.0` | Compares the version using the given `type` |
+| `~3.11` | Same minor version: `>=3.11.0 <3.12.0` (`~3` is `>=3.0.0 <4.0.0`) |
+| `^2` | Same first non-zero segment: `>=2.0.0 <3.0.0` (`^0.2.3` is `>=0.2.3 <0.3.0`) |
+| `~=2.2` | Compatible release: `>=2.2 <3.0` (`~=1.4.5` is `>=1.4.5 <1.5`) |
+| `1.28.x`, `1.28.*` | Same as `~1.28` |
+
+For `semver` and `semver_loose` numeric versions with less than three segments are padded with zeros (`>=1.28` becomes `>=1.28.0`). Other types compare against the bound as written as for example `debian` and `rpm` order `1.2` before `1.2.0`: `~1.2` matches `1.2-1` as well as `1.2.5-1`. The upper bounds created by `~`, `^`, `~=` and `.x` do not match pre-releases of the bound: with `allow_prerelease` the range `~1.28` matches `1.28.5-rc.1` but not `1.29.0-rc.1`.
+
+The configuration is validated when it is loaded or reloaded: unknown fetchers, invalid fetcher configurations, invalid transform steps and version constraints with a missing or unknown `type`, a `range` not parseable for the given `type` or containing an alternative no version can satisfy (`>=2.0 <1.0`) are rejected, naming the `name:tag` of the affected catalog entry. For `allow_prerelease` on a `type` without pre-release markers (`numeric_dot`) a warning is logged as it has no effect.
+
+Some fetchers (`atlassian`, `git_tag`, `github_release` and `helm`) are able to list all available versions instead of only the latest one. For those a `version_constraint` does not only decide whether to apply the version the fetcher considers the latest one: all listed versions are transformed, versions not parseable with the given `type` (or failing a transform step) are ignored and the highest version allowed by the constraint is chosen. This for example allows to track the newest stable release of a repository also publishing pre-releases. Versions marked as pre-release by the source (for example GitHub releases flagged as pre-release) are only chosen with `allow_prerelease` even if the version itself does not look like a pre-release.
+
+When the constraint prevents a newer version from being applied, the version is stored as `blocked_version` together with a `blocked_reason` (`downgrade`, `prerelease` or `out_of_range`) and shown in the API, the web interface and the badge (`1.3.0 (1.4.0-rc2 not applied)`, add `?blocked=false` to the badge URL to hide it, versions `out_of_range` are not shown in the badge). For fetchers listing all versions the highest listed version inside the `range` is reported if no version can be applied, versions outside the `range` are not reported. The blocked version is cleared as soon as a version is applied or the fetched version equals the current one.
 
 ## Available Fetchers
 
@@ -20,6 +20,7 @@ require (
 	github.com/sirupsen/logrus v1.10.1
 	github.com/stretchr/testify v1.12.1
 	github.com/tdewolff/minify/v2 v2.24.17
+	go.etcd.io/bbolt v1.4.3
 	golang.org/x/image v0.45.0
 	golang.org/x/net v0.58.0
 	gopkg.in/yaml.v3 v3.0.1
@@ -6,8 +6,11 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"strings"
 	"time"
 
+	"github.com/robfig/cron/v3"
+	"github.com/sirupsen/logrus"
 	"gopkg.in/yaml.v3"
 
 	"github.com/Luzifer/go-latestver/internal/database"
@@ -29,9 +32,7 @@ var ErrCatalogEntryNotFound = errors.New("catalog entry not found")
 
 // New creates a new empty File object with defaults
 func New() *File {
-	return &File{
-		CheckInterval: time.Hour,
-	}
+	return &File{}
 }
 
 // CatalogEntryByTag retrieves a catalog entry by its name or returns
@@ -66,18 +67,74 @@ func (f *File) Load(filepath string) error {
 	return nil
 }
 
-// ValidateCatalog checks whether invalid fetchers are used or the
-// configuration of the fetcher is not suitable for the given fetcher
+// ValidateCatalog checks whether invalid fetchers are used, the
+// configuration of the fetcher is not suitable for the given fetcher,
+// the check interval / schedule, the webhook, the version transform,
+// the version constraint or a label is invalid
 func (f File) ValidateCatalog() error {
-	for i, ce := range f.Catalog {
+	if f.CheckInterval < 0 {
+		return errors.New("check_interval must not be negative")
+	}
+
+	for _, ce := range f.Catalog {
 		fi := fetcher.Get(ce.Fetcher)
 		if fi == nil {
-			return fmt.Errorf("catalog entry %d has unknown fetcher", i)
+			return fmt.Errorf("catalog entry %q has unknown fetcher %q", ce.Key(), ce.Fetcher)
 		}
 
 		if err := fi.Validate(ce.FetcherConfig); err != nil {
-			return fmt.Errorf("catalog entry %d has invalid fetcher config: %w", i, err)
+			return fmt.Errorf("catalog entry %q has invalid fetcher config: %w", ce.Key(), err)
+		}
+
+		if err := validateCheckTiming(ce); err != nil {
+			return fmt.Errorf("catalog entry %q has invalid check timing: %w", ce.Key(), err)
+		}
+
+		if ce.Webhook != nil && (ce.Webhook.Repository == "" || ce.Webhook.Secret == "") {
+			return fmt.Errorf("catalog entry %q has invalid webhook: repository and secret are required", ce.Key())
 		}
+
+		if err := ce.Transform.Validate(); err != nil {
+			return fmt.Errorf("catalog entry %q has invalid transform: %w", ce.Key(), err)
+		}
+
+		if ce.VersionConstraint != nil {
+			if err := ce.VersionConstraint.Validate(); err != nil {
+				return fmt.Errorf("catalog entry %q has invalid version constraint: %w", ce.Key(), err)
+			}
+
+			if ce.VersionConstraint.PrereleaseIgnored() {
+				logrus.WithField("entry", ce.Key()).
+					Warnf("allow_prerelease has no effect for version type %q without pre-release markers", ce.VersionConstraint.Type)
+			}
+		}
+
+		for k := range ce.Labels {
+			// Label filters are given as key=value
+			if k == "" || strings.Contains(k, "=") {
+				return fmt.Errorf("catalog entry %q has invalid label %q: key must not be empty or contain '='", ce.Key(), k)
+			}
+		}
+	}
+
+	return nil
+}
+
+func validateCheckTiming(ce database.CatalogEntry) error {
+	if ce.CheckInterval < 0 {
+		return errors.New("check_interval must not be negative")
+	}
+
+	if ce.CheckSchedule == "" {
+		return nil
+	}
+
+	if ce.CheckInterval > 0 {
+		return errors.New("check_interval and check_schedule are mutually exclusive")
+	}
+
+	if _, err := cron.ParseStandard(ce.CheckSchedule); err != nil {
+		return fmt.Errorf("parsing check_schedule: %w", err)
 	}
 
 	return nil
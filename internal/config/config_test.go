@@ -0,0 +1,137 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestValidateCatalog(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		config string
+		errMsg string
+	}{
+		{
+			"valid",
+			`catalog:
+  - name: app
+    tag: stable
+    fetcher: regex
+    fetcher_config: { url: "https://example.com", regex: "v([0-9.]+)" }
+    check_schedule: "0 4 * * *"
+    version_constraint: { type: semver, range: "^1" }
+    group: platform
+    labels: { team: platform }`,
+			"",
+		},
+		{
+			"unknown fetcher",
+			`catalog:
+  - { name: app, tag: stable, fetcher: unknown }`,
+			`catalog entry "app:stable" has unknown fetcher "unknown"`,
+		},
+		{
+			"unknown version type",
+			`catalog:
+  - name: app
+    tag: stable
+    fetcher: regex
+    fetcher_config: { url: "https://example.com", regex: "v([0-9.]+)" }
+    version_constraint: { type: semvar }`,
+			`catalog entry "app:stable" has invalid version constraint: unknown version type "semvar"`,
+		},
+		{
+			"invalid range",
+			`catalog:
+  - name: app
+    tag: stable
+    fetcher: regex
+    fetcher_config: { url: "https://example.com", regex: "v([0-9.]+)" }
+    version_constraint: { type: semver, range: ">= <2" }`,
+			`catalog entry "app:stable" has invalid version constraint: parsing range`,
+		},
+		{
+			"prerelease without markers",
+			`catalog:
+  - name: app
+    tag: stable
+    fetcher: regex
+    fetcher_config: { url: "https://example.com", regex: "v([0-9.]+)" }
+    version_constraint: { type: numeric_dot, allow_prerelease: true }`,
+			"",
+		},
+		{
+			"unsatisfiable range",
+			`catalog:
+  - name: app
+    tag: stable
+    fetcher: regex
+    fetcher_config: { url: "https://example.com", regex: "v([0-9.]+)" }
+    version_constraint: { type: semver, range: ">=2.0 <1.0" }`,
+			`catalog entry "app:stable" has invalid version constraint: range ">=2.0 <1.0" contains an alternative no version can satisfy`,
+		},
+		{
+			"interval and schedule",
+			`catalog:
+  - name: app
+    tag: stable
+    fetcher: regex
+    fetcher_config: { url: "https://example.com", regex: "v([0-9.]+)" }
+    check_interval: 15m
+    check_schedule: "@daily"`,
+			`catalog entry "app:stable" has invalid check timing: check_interval and check_schedule are mutually exclusive`,
+		},
+		{
+			"invalid schedule",
+			`catalog:
+  - name: app
+    tag: stable
+    fetcher: regex
+    fetcher_config: { url: "https://example.com", regex: "v([0-9.]+)" }
+    check_schedule: "0 4 * *"`,
+			`catalog entry "app:stable" has invalid check timing: parsing check_schedule`,
+		},
+		{
+			"webhook without secret",
+			`catalog:
+  - name: app
+    tag: stable
+    fetcher: regex
+    fetcher_config: { url: "https://example.com", regex: "v([0-9.]+)" }
+    webhook: { repository: org/app }`,
+			`catalog entry "app:stable" has invalid webhook: repository and secret are required`,
+		},
+		{
+			"invalid label",
+			`catalog:
+  - name: app
+    tag: stable
+    fetcher: regex
+    fetcher_config: { url: "https://example.com", regex: "v([0-9.]+)" }
+    labels: { "team=platform": "yes" }`,
+			`catalog entry "app:stable" has invalid label "team=platform"`,
+		},
+	} {
+		cfgFile := filepath.Join(t.TempDir(), "config.yaml")
+		if err := os.WriteFile(cfgFile, []byte(tc.config), 0o600); err != nil {
+			t.Fatalf("writing config: %s", err)
+		}
+
+		f := New()
+		if err := f.Load(cfgFile); err != nil {
+			t.Fatalf("%s: loading config: %s", tc.name, err)
+		}
+
+		err := f.ValidateCatalog()
+		switch {
+		case tc.errMsg == "" && err != nil:
+			t.Errorf("%s: expected valid config, got %s", tc.name, err)
+		case tc.errMsg != "" && err == nil:
+			t.Errorf("%s: expected validation error", tc.name)
+		case tc.errMsg != "" && !strings.HasPrefix(err.Error(), tc.errMsg):
+			t.Errorf("%s: unexpected error %q", tc.name, err)
+		}
+	}
+}
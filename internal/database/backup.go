@@ -0,0 +1,176 @@
+package database
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+const (
+	// backupFormatVersion is increased on incompatible changes of the
+	// backup format
+	backupFormatVersion = 1
+
+	backupRecordCatalogMeta = "catalog_meta"
+	backupRecordHeader      = "header"
+	backupRecordLogEntry    = "log_entry"
+)
+
+type (
+	// BackupResult contains the number of records exported or imported
+	BackupResult struct {
+		CatalogMetas int64
+		LogEntries   int64
+		// Skipped contains the number of imported records already
+		// present (log entries) or outdated (catalog meta)
+		Skipped int64
+	}
+
+	// backupMeta exposes the catalog key hidden in the JSON
+	// representation of the CatalogMeta
+	backupMeta struct {
+		CatalogName string `json:"catalog_name"`
+		CatalogTag  string `json:"catalog_tag"`
+		CatalogMeta
+	}
+
+	// backupRecord is a single line in the newline-delimited JSON
+	// backup: the first record is the header, followed by all catalog
+	// meta and log entries
+	backupRecord struct {
+		Type string `json:"type"`
+
+		Version     int         `json:"version,omitempty"`
+		CatalogMeta *backupMeta `json:"catalog_meta,omitempty"`
+		LogEntry    *LogEntry   `json:"log_entry,omitempty"`
+	}
+)
+
+// ErrInvalidBackup signals the data passed to Client.Import is not a
+// backup created by Client.Export or of an unsupported version
+var ErrInvalidBackup = errors.New("invalid backup")
+
+// Export writes all catalog meta and log entries as newline-delimited
+// JSON into the writer
+func (c Client) Export(w io.Writer) (res BackupResult, err error) {
+	enc := json.NewEncoder(w)
+
+	if err = enc.Encode(backupRecord{Type: backupRecordHeader, Version: backupFormatVersion}); err != nil {
+		return res, fmt.Errorf("writing header: %w", err)
+	}
+
+	if err = c.backend.eachCatalogMeta(DefaultMigrateBatchSize, func(batch []CatalogMeta) error {
+		for _, cm := range batch {
+			if err := enc.Encode(backupRecord{
+				Type:        backupRecordCatalogMeta,
+				CatalogMeta: &backupMeta{CatalogName: cm.CatalogName, CatalogTag: cm.CatalogTag, CatalogMeta: cm},
+			}); err != nil {
+				return fmt.Errorf("writing catalog meta: %w", err)
+			}
+			res.CatalogMetas++
+		}
+		return nil
+	}); err != nil {
+		return res, fmt.Errorf("exporting catalog meta: %w", err)
+	}
+
+	if err = c.backend.eachLogEntry(DefaultMigrateBatchSize, func(batch []LogEntry) error {
+		for i := range batch {
+			if err := enc.Encode(backupRecord{Type: backupRecordLogEntry, LogEntry: &batch[i]}); err != nil {
+				return fmt.Errorf("writing log entry: %w", err)
+			}
+			res.LogEntries++
+		}
+		return nil
+	}); err != nil {
+		return res, fmt.Errorf("exporting log entries: %w", err)
+	}
+
+	return res, nil
+}
+
+// Import merges a backup created by Export into the database: log
+// entries already present are skipped and catalog meta is only
+// replaced if the imported one was checked more recently. The import
+// is executed in a single transaction.
+func (c Client) Import(r io.Reader) (res BackupResult, err error) {
+	dec := json.NewDecoder(r)
+
+	var header backupRecord
+	if err = dec.Decode(&header); err != nil {
+		return res, fmt.Errorf("%w: reading header: %w", ErrInvalidBackup, err)
+	}
+
+	if header.Type != backupRecordHeader || header.Version != backupFormatVersion {
+		return res, fmt.Errorf("%w: unsupported header %s/%d", ErrInvalidBackup, header.Type, header.Version)
+	}
+
+	if err = c.Transaction(func(tx *Client) error {
+		for {
+			var rec backupRecord
+			if err := dec.Decode(&rec); errors.Is(err, io.EOF) {
+				return nil
+			} else if err != nil {
+				return fmt.Errorf("%w: reading record: %w", ErrInvalidBackup, err)
+			}
+
+			if err := tx.importRecord(rec, &res); err != nil {
+				return err
+			}
+		}
+	}); err != nil {
+		return BackupResult{}, fmt.Errorf("importing backup: %w", err)
+	}
+
+	return res, nil
+}
+
+func (c Client) importRecord(rec backupRecord, res *BackupResult) error {
+	switch {
+	case rec.Type == backupRecordCatalogMeta && rec.CatalogMeta != nil:
+		cm := rec.CatalogMeta.CatalogMeta
+		cm.CatalogName, cm.CatalogTag = rec.CatalogMeta.CatalogName, rec.CatalogMeta.CatalogTag
+
+		existing, err := c.Catalog.GetMeta(&CatalogEntry{Name: cm.CatalogName, Tag: cm.CatalogTag})
+		if err != nil {
+			return fmt.Errorf("fetching existing catalog meta: %w", err)
+		}
+
+		if existing.LastChecked != nil && (cm.LastChecked == nil || existing.LastChecked.After(*cm.LastChecked)) {
+			// Our data is more recent than the imported one
+			res.Skipped++
+			return nil
+		}
+
+		if err = c.Catalog.PutMeta(&cm); err != nil {
+			return fmt.Errorf("storing catalog meta: %w", err)
+		}
+		res.CatalogMetas++
+
+	case rec.Type == backupRecordLogEntry && rec.LogEntry != nil:
+		le := *rec.LogEntry
+
+		exists, err := c.backend.hasLogEntry(le)
+		if err != nil {
+			return fmt.Errorf("checking for existing log entry: %w", err)
+		}
+
+		if exists {
+			res.Skipped++
+			return nil
+		}
+
+		// IDs of the source database would collide with our own entries
+		le.ID = 0
+		if err := c.Logs.Add(&le); err != nil {
+			return fmt.Errorf("storing log entry: %w", err)
+		}
+		res.LogEntries++
+
+	default:
+		return fmt.Errorf("%w: unexpected record of type %q", ErrInvalidBackup, rec.Type)
+	}
+
+	return nil
+}
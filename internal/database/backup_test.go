@@ -0,0 +1,108 @@
+package database
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_ExportImport(t *testing.T) {
+	src, err := NewClient("sqlite3", "file:backupsrc?mode=memory&cache=shared")
+	if err != nil {
+		t.Fatalf("unable to create source client: %s", err)
+	}
+	t.Cleanup(func() { _ = src.Close() })
+
+	dest, err := NewClient("sqlite3", "file:backupdest?mode=memory&cache=shared")
+	if err != nil {
+		t.Fatalf("unable to create dest client: %s", err)
+	}
+	t.Cleanup(func() { _ = dest.Close() })
+
+	var (
+		ce    = CatalogEntry{Name: "app", Tag: "latest"}
+		other = CatalogEntry{Name: "other", Tag: "latest"}
+		now   = time.Now().UTC()
+		older = now.Add(-time.Hour)
+	)
+
+	for _, cm := range []*CatalogMeta{
+		{CatalogName: ce.Name, CatalogTag: ce.Tag, CurrentVersion: "1.1.0", LastChecked: &now},
+		{CatalogName: other.Name, CatalogTag: other.Tag, CurrentVersion: "1.0.0", LastChecked: &older},
+	} {
+		if err = src.Catalog.PutMeta(cm); err != nil {
+			t.Fatalf("adding meta: %s", err)
+		}
+	}
+
+	for _, le := range []*LogEntry{
+		{CatalogName: ce.Name, CatalogTag: ce.Tag, Timestamp: older, VersionFrom: "", VersionTo: "1.0.0"},
+		{CatalogName: ce.Name, CatalogTag: ce.Tag, Timestamp: now, VersionFrom: "1.0.0", VersionTo: "1.1.0"},
+	} {
+		if err = src.Logs.Add(le); err != nil {
+			t.Fatalf("adding log entry: %s", err)
+		}
+	}
+
+	// The destination has its own history and more recent meta for one
+	// of the entries which must be kept
+	if err = dest.Logs.Add(&LogEntry{CatalogName: other.Name, CatalogTag: other.Tag, Timestamp: older, VersionTo: "1.0.0"}); err != nil {
+		t.Fatalf("adding log entry: %s", err)
+	}
+
+	if err = dest.Catalog.PutMeta(&CatalogMeta{CatalogName: other.Name, CatalogTag: other.Tag, CurrentVersion: "2.0.0", LastChecked: &now}); err != nil {
+		t.Fatalf("adding meta: %s", err)
+	}
+
+	buf := new(bytes.Buffer)
+	exported, err := src.Export(buf)
+	if err != nil {
+		t.Fatalf("exporting: %s", err)
+	}
+
+	if exported.CatalogMetas != 2 || exported.LogEntries != 2 {
+		t.Errorf("unexpected export result: %+v", exported)
+	}
+
+	if lines := strings.Count(buf.String(), "\n"); lines != 5 {
+		t.Errorf("expected 5 lines in export, got %d", lines)
+	}
+
+	for i, expect := range []BackupResult{
+		{CatalogMetas: 1, LogEntries: 2, Skipped: 1},
+		{CatalogMetas: 1, Skipped: 3},
+	} {
+		res, err := dest.Import(bytes.NewReader(buf.Bytes()))
+		if err != nil {
+			t.Fatalf("importing (run %d): %s", i, err)
+		}
+
+		if res != expect {
+			t.Errorf("unexpected import result (run %d): %+v", i, res)
+		}
+	}
+
+	logs, err := dest.Logs.List(100, 0)
+	if err != nil {
+		t.Fatalf("listing logs: %s", err)
+	}
+
+	if len(logs) != 3 {
+		t.Errorf("expected 3 log entries after import, got %d", len(logs))
+	}
+
+	cm, err := dest.Catalog.GetMeta(&other)
+	if err != nil {
+		t.Fatalf("fetching meta: %s", err)
+	}
+
+	if cm.CurrentVersion != "2.0.0" {
+		t.Errorf("more recent meta was overwritten: %s", cm.CurrentVersion)
+	}
+
+	if _, err = dest.Import(strings.NewReader(`{"type":"log_entry"}`)); !errors.Is(err, ErrInvalidBackup) {
+		t.Errorf("expected ErrInvalidBackup for missing header, got %v", err)
+	}
+}
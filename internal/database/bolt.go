@@ -0,0 +1,619 @@
+package database
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+const (
+	boltFileMode    = 0o600
+	boltOpenTimeout = time.Second
+)
+
+// Buckets used in the bolt database: log entries are indexed by their
+// timestamp and ID in the log index to query them in log order
+var (
+	boltBucketCatalogMeta   = []byte("catalog_meta")
+	boltBucketCheckAttempts = []byte("check_attempts")
+	boltBucketLeases        = []byte("leases")
+	boltBucketLogEntries    = []byte("log_entries")
+	boltBucketLogIndex      = []byte("log_index")
+)
+
+type (
+	// boltBackend implements the backend for an embedded bolt database
+	// file, within a transaction all operations use the given tx
+	boltBackend struct {
+		db *bolt.DB
+		tx *bolt.Tx
+	}
+
+	// boltCheckAttemptKey identifies a check attempt independent of its
+	// ID, the start time is stored in milliseconds as it might have
+	// been truncated by other databases
+	boltCheckAttemptKey struct {
+		catalogKey
+		started int64
+	}
+)
+
+func newBoltClient(path string) (*Client, error) {
+	db, err := bolt.Open(path, boltFileMode, &bolt.Options{Timeout: boltOpenTimeout})
+	if err != nil {
+		return nil, fmt.Errorf("opening bolt database: %w", err)
+	}
+
+	if err = db.Update(func(tx *bolt.Tx) error {
+		for _, name := range [][]byte{
+			boltBucketCatalogMeta,
+			boltBucketCheckAttempts,
+			boltBucketLeases,
+			boltBucketLogEntries,
+			boltBucketLogIndex,
+		} {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return fmt.Errorf("creating bucket %q: %w", name, err)
+			}
+		}
+		return nil
+	}); err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("initializing bolt database: %w", err)
+	}
+
+	return newBoltClientForBackend(boltBackend{db: db}), nil
+}
+
+func newBoltClientForBackend(b boltBackend) *Client {
+	return &Client{
+		Catalog: boltCatalogMetaStore{b},
+		Checks:  boltCheckStore{b},
+		Leases:  boltLeaseStore{b},
+		Logs:    boltLogStore{b},
+
+		backend: b,
+	}
+}
+
+func (b boltBackend) close() error {
+	if err := b.db.Close(); err != nil {
+		return fmt.Errorf("closing bolt database: %w", err)
+	}
+
+	return nil
+}
+
+func (b boltBackend) eachCatalogMeta(batchSize int, fn func([]CatalogMeta) error) error {
+	return boltEach(b, boltBucketCatalogMeta, batchSize, decodeBoltCatalogMeta, fn)
+}
+
+func (b boltBackend) eachCheckAttempt(batchSize int, fn func([]CheckAttempt) error) error {
+	return boltEach(b, boltBucketCheckAttempts, batchSize, decodeBoltCheckAttempt, fn)
+}
+
+func (b boltBackend) eachLogEntry(batchSize int, fn func([]LogEntry) error) error {
+	return boltEach(b, boltBucketLogEntries, batchSize, decodeBoltLogEntry, fn)
+}
+
+func (b boltBackend) hasCheckAttempt(ca CheckAttempt) (exists bool, err error) {
+	err = b.view(func(tx *bolt.Tx) error {
+		stored, err := boltCheckAttemptKeys(tx)
+		exists = stored[boltCheckAttemptKeyOf(ca)]
+		return err
+	})
+
+	return exists, err
+}
+
+func (b boltBackend) hasLogEntry(le LogEntry) (exists bool, err error) {
+	// Timestamps of imported entries may have been truncated by other
+	// databases so entries within the same millisecond are compared
+	ts := le.Timestamp.Truncate(time.Millisecond)
+
+	err = b.view(func(tx *bolt.Tx) error {
+		logs := tx.Bucket(boltBucketLogEntries)
+		upper := boltLogIndexKey(ts.Add(time.Millisecond), 0)
+
+		c := tx.Bucket(boltBucketLogIndex).Cursor()
+		for k, _ := c.Seek(boltLogIndexKey(ts, 0)); k != nil && bytes.Compare(k, upper) < 0; k, _ = c.Next() {
+			stored, err := decodeBoltLogEntry(k[8:], logs.Get(k[8:]))
+			if err != nil {
+				return err
+			}
+
+			if stored.CatalogName == le.CatalogName && stored.CatalogTag == le.CatalogTag &&
+				stored.VersionFrom == le.VersionFrom && stored.VersionTo == le.VersionTo {
+				exists = true
+				return nil
+			}
+		}
+		return nil
+	})
+
+	return exists, err
+}
+
+func (b boltBackend) insertCheckAttempts(checks []CheckAttempt) (inserted int64, err error) {
+	err = b.update(func(tx *bolt.Tx) error {
+		// Check attempts are not indexed so the stored ones are read once
+		// for the whole batch
+		stored, err := boltCheckAttemptKeys(tx)
+		if err != nil {
+			return err
+		}
+
+		for _, ca := range checks {
+			key := boltCheckAttemptKeyOf(ca)
+			if stored[key] {
+				continue
+			}
+
+			ca.ID = 0
+			if err = boltPutCheckAttempt(tx, &ca); err != nil {
+				return err
+			}
+			stored[key] = true
+			inserted++
+		}
+		return nil
+	})
+
+	return inserted, err
+}
+
+func (b boltBackend) insertLogEntries(logs []LogEntry) (inserted int64, err error) {
+	err = b.update(func(tx *bolt.Tx) error {
+		txb := boltBackend{db: b.db, tx: tx}
+
+		for _, le := range logs {
+			exists, err := txb.hasLogEntry(le)
+			if err != nil {
+				return err
+			}
+
+			if exists {
+				continue
+			}
+
+			le.ID = 0
+			if err = boltPutLogEntry(tx, &le); err != nil {
+				return err
+			}
+			inserted++
+		}
+		return nil
+	})
+
+	return inserted, err
+}
+
+func (b boltBackend) prune(opts PruneOptions) (res PruneResult, err error) {
+	err = b.update(func(tx *bolt.Tx) error {
+		if opts.RemoveOrphans {
+			orphans, err := boltPruneOrphans(tx, opts.Catalog)
+			if err != nil {
+				return fmt.Errorf("removing orphans: %w", err)
+			}
+			res = orphans
+		}
+
+		if opts.CheckMaxAge > 0 {
+			n, err := boltPruneCheckAttempts(tx, time.Now().Add(-opts.CheckMaxAge))
+			if err != nil {
+				return fmt.Errorf("removing old check attempts: %w", err)
+			}
+			res.Checks += n
+		}
+
+		if opts.LogMaxAge > 0 || opts.LogMaxEntries > 0 {
+			n, err := boltPruneLogEntries(tx, opts.LogMaxAge, opts.LogMaxEntries)
+			if err != nil {
+				return fmt.Errorf("removing log entries: %w", err)
+			}
+			res.Logs += n
+		}
+
+		return nil
+	})
+
+	return res, err
+}
+
+func (b boltBackend) transaction(fn func(tx *Client) error) error {
+	if b.tx != nil {
+		// Already within a transaction
+		return fn(newBoltClientForBackend(b))
+	}
+
+	return b.db.Update(func(tx *bolt.Tx) error { //nolint:wrapcheck // wrapped by Client.Transaction
+		return fn(newBoltClientForBackend(boltBackend{db: b.db, tx: tx}))
+	})
+}
+
+// update executes fn within the current transaction or a new
+// read-write transaction
+func (b boltBackend) update(fn func(tx *bolt.Tx) error) error {
+	if b.tx != nil {
+		return fn(b.tx)
+	}
+
+	return b.db.Update(fn) //nolint:wrapcheck // errors are wrapped by the callers
+}
+
+func (b boltBackend) upsertCatalogMetas(metas []CatalogMeta) (int64, error) {
+	err := b.update(func(tx *bolt.Tx) error {
+		for i := range metas {
+			if err := boltPutCatalogMeta(tx, &metas[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+
+	return int64(len(metas)), err
+}
+
+// view executes fn within the current transaction or a new read-only
+// transaction
+func (b boltBackend) view(fn func(tx *bolt.Tx) error) error {
+	if b.tx != nil {
+		return fn(b.tx)
+	}
+
+	return b.db.View(fn) //nolint:wrapcheck // errors are wrapped by the callers
+}
+
+// boltEach reads all values of the bucket in key order and passes them
+// to fn in batches. Every batch is read in its own transaction which
+// is closed before calling fn, so a slow consumer (like a client
+// downloading an export) does not block writers.
+func boltEach[T any](b boltBackend, bucket []byte, batchSize int, decode func(k, v []byte) (T, error), fn func([]T) error) error {
+	if batchSize <= 0 {
+		batchSize = DefaultMigrateBatchSize
+	}
+
+	var lastKey []byte
+	for {
+		batch := make([]T, 0, batchSize)
+
+		if err := b.view(func(tx *bolt.Tx) error {
+			c := tx.Bucket(bucket).Cursor()
+
+			k, v := c.First()
+			if lastKey != nil {
+				// Continue after the last key of the previous batch
+				if k, v = c.Seek(lastKey); k != nil && bytes.Equal(k, lastKey) {
+					k, v = c.Next()
+				}
+			}
+
+			for ; k != nil && len(batch) < batchSize; k, v = c.Next() {
+				row, err := decode(k, v)
+				if err != nil {
+					return err
+				}
+
+				batch = append(batch, row)
+				// Keys are only valid during the transaction
+				lastKey = bytes.Clone(k)
+			}
+
+			return nil
+		}); err != nil {
+			return err
+		}
+
+		if len(batch) == 0 {
+			return nil
+		}
+
+		if err := fn(batch); err != nil {
+			return err
+		}
+
+		if len(batch) < batchSize {
+			return nil
+		}
+	}
+}
+
+func boltCatalogMetaKey(name, tag string) []byte {
+	return []byte(name + "\x00" + tag)
+}
+
+func boltCheckAttemptKeyOf(ca CheckAttempt) boltCheckAttemptKey {
+	return boltCheckAttemptKey{catalogKey{ca.CatalogName, ca.CatalogTag}, ca.Started.UnixMilli()}
+}
+
+// boltCheckAttemptKeys collects the keys of all stored check attempts
+func boltCheckAttemptKeys(tx *bolt.Tx) (map[boltCheckAttemptKey]bool, error) {
+	keys := make(map[boltCheckAttemptKey]bool)
+
+	err := tx.Bucket(boltBucketCheckAttempts).ForEach(func(k, v []byte) error {
+		ca, err := decodeBoltCheckAttempt(k, v)
+		keys[boltCheckAttemptKeyOf(ca)] = true
+		return err
+	})
+
+	return keys, err //nolint:wrapcheck // errors are wrapped by the callers
+}
+
+func boltDeleteLogEntry(tx *bolt.Tx, le LogEntry) error {
+	if err := tx.Bucket(boltBucketLogIndex).Delete(boltLogIndexKey(le.Timestamp, le.ID)); err != nil {
+		return fmt.Errorf("deleting log index: %w", err)
+	}
+
+	if err := tx.Bucket(boltBucketLogEntries).Delete(boltID(le.ID)); err != nil {
+		return fmt.Errorf("deleting log entry: %w", err)
+	}
+
+	return nil
+}
+
+func boltID(id uint64) []byte {
+	return binary.BigEndian.AppendUint64(nil, id)
+}
+
+// boltLogIndexKey creates the key for the log index which sorts the
+// entries by timestamp and ID
+func boltLogIndexKey(ts time.Time, id uint64) []byte {
+	return binary.BigEndian.AppendUint64(boltID(uint64(ts.UnixNano())), id) //nolint:gosec // timestamps before 1970 are not expected
+}
+
+func boltPruneCheckAttempts(tx *bolt.Tx, before time.Time) (n int64, err error) {
+	var remove [][]byte
+
+	bucket := tx.Bucket(boltBucketCheckAttempts)
+	if err = bucket.ForEach(func(k, v []byte) error {
+		ca, err := decodeBoltCheckAttempt(k, v)
+		if err != nil {
+			return err
+		}
+
+		if ca.Started.Before(before) {
+			remove = append(remove, k)
+		}
+		return nil
+	}); err != nil {
+		return 0, fmt.Errorf("listing check attempts: %w", err)
+	}
+
+	for _, k := range remove {
+		if err = bucket.Delete(k); err != nil {
+			return n, fmt.Errorf("deleting check attempt: %w", err)
+		}
+		n++
+	}
+
+	return n, nil
+}
+
+// boltPruneLogEntries removes log entries older than maxAge and all
+// but the newest maxEntries entries for every catalog entry
+func boltPruneLogEntries(tx *bolt.Tx, maxAge time.Duration, maxEntries int) (n int64, err error) {
+	var (
+		cutoff = boltLogIndexKey(time.Now().Add(-maxAge), 0)
+		logs   = tx.Bucket(boltBucketLogEntries)
+		perKey = map[catalogKey]int{}
+		remove []LogEntry
+	)
+
+	c := tx.Bucket(boltBucketLogIndex).Cursor()
+	for k, _ := c.Last(); k != nil; k, _ = c.Prev() {
+		le, err := decodeBoltLogEntry(k[8:], logs.Get(k[8:]))
+		if err != nil {
+			return 0, err
+		}
+
+		key := catalogKey{le.CatalogName, le.CatalogTag}
+		perKey[key]++
+
+		if (maxAge > 0 && bytes.Compare(k, cutoff) < 0) || (maxEntries > 0 && perKey[key] > maxEntries) {
+			remove = append(remove, le)
+		}
+	}
+
+	for _, le := range remove {
+		if err = boltDeleteLogEntry(tx, le); err != nil {
+			return n, err
+		}
+		n++
+	}
+
+	return n, nil
+}
+
+func boltPruneOrphans(tx *bolt.Tx, catalog []CatalogEntry) (res PruneResult, err error) {
+	known := make(map[catalogKey]bool, len(catalog))
+	for _, ce := range catalog {
+		known[catalogKey{ce.Name, ce.Tag}] = true
+	}
+
+	var (
+		checks [][]byte
+		logs   []LogEntry
+		metas  [][]byte
+	)
+
+	if err = tx.Bucket(boltBucketCatalogMeta).ForEach(func(k, v []byte) error {
+		cm, err := decodeBoltCatalogMeta(k, v)
+		if err == nil && !known[catalogKey{cm.CatalogName, cm.CatalogTag}] {
+			metas = append(metas, k)
+		}
+		return err
+	}); err != nil {
+		return res, fmt.Errorf("listing catalog meta: %w", err)
+	}
+
+	if err = tx.Bucket(boltBucketCheckAttempts).ForEach(func(k, v []byte) error {
+		ca, err := decodeBoltCheckAttempt(k, v)
+		if err == nil && !known[catalogKey{ca.CatalogName, ca.CatalogTag}] {
+			checks = append(checks, k)
+		}
+		return err
+	}); err != nil {
+		return res, fmt.Errorf("listing check attempts: %w", err)
+	}
+
+	if err = tx.Bucket(boltBucketLogEntries).ForEach(func(k, v []byte) error {
+		le, err := decodeBoltLogEntry(k, v)
+		if err == nil && !known[catalogKey{le.CatalogName, le.CatalogTag}] {
+			logs = append(logs, le)
+		}
+		return err
+	}); err != nil {
+		return res, fmt.Errorf("listing log entries: %w", err)
+	}
+
+	for _, k := range metas {
+		if err = tx.Bucket(boltBucketCatalogMeta).Delete(k); err != nil {
+			return res, fmt.Errorf("deleting catalog meta: %w", err)
+		}
+		res.Metas++
+	}
+
+	for _, k := range checks {
+		if err = tx.Bucket(boltBucketCheckAttempts).Delete(k); err != nil {
+			return res, fmt.Errorf("deleting check attempt: %w", err)
+		}
+		res.Checks++
+	}
+
+	for _, le := range logs {
+		if err = boltDeleteLogEntry(tx, le); err != nil {
+			return res, err
+		}
+		res.Logs++
+	}
+
+	return res, nil
+}
+
+func boltPutCatalogMeta(tx *bolt.Tx, cm *CatalogMeta) error {
+	// Name and tag are part of the key and not contained in the JSON
+	data, err := json.Marshal(cm)
+	if err != nil {
+		return fmt.Errorf("encoding catalog meta: %w", err)
+	}
+
+	if err = tx.Bucket(boltBucketCatalogMeta).Put(boltCatalogMetaKey(cm.CatalogName, cm.CatalogTag), data); err != nil {
+		return fmt.Errorf("writing catalog meta: %w", err)
+	}
+
+	return nil
+}
+
+func boltPutCheckAttempt(tx *bolt.Tx, ca *CheckAttempt) error {
+	bucket := tx.Bucket(boltBucketCheckAttempts)
+	if err := boltReserveID(bucket, &ca.ID); err != nil {
+		return err
+	}
+
+	// The ID is part of the key and not contained in the JSON
+	data, err := json.Marshal(ca)
+	if err != nil {
+		return fmt.Errorf("encoding check attempt: %w", err)
+	}
+
+	if err = bucket.Put(boltID(ca.ID), data); err != nil {
+		return fmt.Errorf("writing check attempt: %w", err)
+	}
+
+	return nil
+}
+
+func boltPutLogEntry(tx *bolt.Tx, le *LogEntry) error {
+	bucket := tx.Bucket(boltBucketLogEntries)
+	if err := boltReserveID(bucket, &le.ID); err != nil {
+		return err
+	}
+
+	// Remove the index of the entry we are about to replace as its
+	// timestamp might have changed
+	if existing := bucket.Get(boltID(le.ID)); existing != nil {
+		old, err := decodeBoltLogEntry(boltID(le.ID), existing)
+		if err != nil {
+			return err
+		}
+
+		if err = tx.Bucket(boltBucketLogIndex).Delete(boltLogIndexKey(old.Timestamp, old.ID)); err != nil {
+			return fmt.Errorf("deleting log index: %w", err)
+		}
+	}
+
+	data, err := json.Marshal(le)
+	if err != nil {
+		return fmt.Errorf("encoding log entry: %w", err)
+	}
+
+	if err = bucket.Put(boltID(le.ID), data); err != nil {
+		return fmt.Errorf("writing log entry: %w", err)
+	}
+
+	if err = tx.Bucket(boltBucketLogIndex).Put(boltLogIndexKey(le.Timestamp, le.ID), nil); err != nil {
+		return fmt.Errorf("writing log index: %w", err)
+	}
+
+	return nil
+}
+
+// boltReserveID assigns the next ID of the bucket if the given ID is
+// not set and otherwise ensures the sequence will not hand out the
+// given ID in the future
+func boltReserveID(bucket *bolt.Bucket, id *uint64) (err error) {
+	if *id == 0 {
+		if *id, err = bucket.NextSequence(); err != nil {
+			return fmt.Errorf("getting next ID: %w", err)
+		}
+		return nil
+	}
+
+	if *id > bucket.Sequence() {
+		if err = bucket.SetSequence(*id); err != nil {
+			return fmt.Errorf("updating sequence: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func decodeBoltCatalogMeta(k, v []byte) (cm CatalogMeta, err error) {
+	if err = json.Unmarshal(v, &cm); err != nil {
+		return cm, fmt.Errorf("decoding catalog meta: %w", err)
+	}
+
+	name, tag, ok := bytes.Cut(k, []byte{0})
+	if !ok {
+		return cm, errors.New("invalid catalog meta key")
+	}
+	cm.CatalogName, cm.CatalogTag = string(name), string(tag)
+
+	return cm, nil
+}
+
+func decodeBoltCheckAttempt(k, v []byte) (ca CheckAttempt, err error) {
+	if err = json.Unmarshal(v, &ca); err != nil {
+		return ca, fmt.Errorf("decoding check attempt: %w", err)
+	}
+	ca.ID = binary.BigEndian.Uint64(k)
+
+	return ca, nil
+}
+
+func decodeBoltLogEntry(_, v []byte) (le LogEntry, err error) {
+	if v == nil {
+		return le, errors.New("log index references missing log entry")
+	}
+
+	if err = json.Unmarshal(v, &le); err != nil {
+		return le, fmt.Errorf("decoding log entry: %w", err)
+	}
+
+	return le, nil
+}
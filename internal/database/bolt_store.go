@@ -0,0 +1,343 @@
+package database
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+type (
+	// boltCatalogMetaStore implements the CatalogMetaStore for bolt
+	boltCatalogMetaStore struct{ b boltBackend }
+
+	// boltCheckStore implements the CheckStore for bolt
+	boltCheckStore struct{ b boltBackend }
+
+	// boltLeaseStore implements the LeaseStore for bolt
+	boltLeaseStore struct{ b boltBackend }
+
+	// boltLogStore implements the LogStore for bolt
+	boltLogStore struct{ b boltBackend }
+)
+
+// GetMeta fetches the current database stored CatalogMeta for the CatalogEntry
+func (c boltCatalogMetaStore) GetMeta(ce *CatalogEntry) (out *CatalogMeta, err error) {
+	out = &CatalogMeta{CatalogName: ce.Name, CatalogTag: ce.Tag}
+
+	if err = c.b.view(func(tx *bolt.Tx) error {
+		k := boltCatalogMetaKey(ce.Name, ce.Tag)
+		v := tx.Bucket(boltBucketCatalogMeta).Get(k)
+		if v == nil {
+			// If there is no meta yet we just return empty meta
+			return nil
+		}
+
+		cm, err := decodeBoltCatalogMeta(k, v)
+		*out = cm
+		return err
+	}); err != nil {
+		return nil, fmt.Errorf("querying metadata: %w", err)
+	}
+
+	return out, nil
+}
+
+// ListMeta fetches all stored CatalogMeta ordered by catalog name and tag
+func (c boltCatalogMetaStore) ListMeta() (out []CatalogMeta, err error) {
+	out = []CatalogMeta{}
+
+	if err = c.b.view(func(tx *bolt.Tx) error {
+		return tx.Bucket(boltBucketCatalogMeta).ForEach(func(k, v []byte) error {
+			cm, err := decodeBoltCatalogMeta(k, v)
+			out = append(out, cm)
+			return err
+		})
+	}); err != nil {
+		return nil, fmt.Errorf("querying metadata: %w", err)
+	}
+
+	return out, nil
+}
+
+// PutMeta stores the updated CatalogMeta
+func (c boltCatalogMetaStore) PutMeta(cm *CatalogMeta) error {
+	if err := c.b.update(func(tx *bolt.Tx) error { return boltPutCatalogMeta(tx, cm) }); err != nil {
+		return fmt.Errorf("storing catalog meta: %w", err)
+	}
+
+	return nil
+}
+
+// Add creates a new CheckAttempt inside the CheckStore
+func (c boltCheckStore) Add(ca *CheckAttempt) error {
+	if err := c.b.update(func(tx *bolt.Tx) error { return boltPutCheckAttempt(tx, ca) }); err != nil {
+		return fmt.Errorf("storing check attempt: %w", err)
+	}
+
+	return nil
+}
+
+// ListForCatalogEntry retrieves the check attempts of the catalog
+// entry by page, latest attempts first
+func (c boltCheckStore) ListForCatalogEntry(ce *CatalogEntry, num, page int) (out []CheckAttempt, err error) {
+	out = []CheckAttempt{}
+
+	if err = c.b.view(func(tx *bolt.Tx) error {
+		return tx.Bucket(boltBucketCheckAttempts).ForEach(func(k, v []byte) error {
+			ca, err := decodeBoltCheckAttempt(k, v)
+			if err == nil && ca.CatalogName == ce.Name && ca.CatalogTag == ce.Tag {
+				out = append(out, ca)
+			}
+			return err
+		})
+	}); err != nil {
+		return nil, fmt.Errorf("fetching check attempts: %w", err)
+	}
+
+	// Checks are stored when finished so the IDs are not necessarily
+	// in the order the checks were started
+	slices.SortFunc(out, func(a, b CheckAttempt) int {
+		if c := b.Started.Compare(a.Started); c != 0 {
+			return c
+		}
+		return int(b.ID) - int(a.ID) //nolint:gosec // IDs will not exceed int range
+	})
+
+	return paginate(out, num, page), nil
+}
+
+// Acquire tries to acquire or renew the lease with the given name for
+// the holder. It returns true if the holder owns the lease for the
+// given TTL afterwards.
+func (l boltLeaseStore) Acquire(name, holder string, ttl time.Duration) (acquired bool, err error) {
+	now := time.Now().UTC()
+
+	if err = l.b.update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(boltBucketLeases)
+
+		if v := bucket.Get([]byte(name)); v != nil {
+			var lease Lease
+			if err := json.Unmarshal(v, &lease); err != nil {
+				return fmt.Errorf("decoding lease: %w", err)
+			}
+
+			if lease.Holder != holder && !lease.ExpiresAt.Before(now) {
+				// Someone else holds a valid lease
+				return nil
+			}
+		}
+
+		data, err := json.Marshal(Lease{Name: name, Holder: holder, ExpiresAt: now.Add(ttl)})
+		if err != nil {
+			return fmt.Errorf("encoding lease: %w", err)
+		}
+
+		acquired = true
+		return bucket.Put([]byte(name), data) //nolint:wrapcheck // wrapped below
+	}); err != nil {
+		return false, fmt.Errorf("acquiring lease: %w", err)
+	}
+
+	return acquired, nil
+}
+
+// Release gives up the lease with the given name if it is owned by
+// the holder
+func (l boltLeaseStore) Release(name, holder string) error {
+	if err := l.b.update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(boltBucketLeases)
+
+		v := bucket.Get([]byte(name))
+		if v == nil {
+			return nil
+		}
+
+		var lease Lease
+		if err := json.Unmarshal(v, &lease); err != nil {
+			return fmt.Errorf("decoding lease: %w", err)
+		}
+
+		if lease.Holder != holder {
+			return nil
+		}
+
+		return bucket.Delete([]byte(name)) //nolint:wrapcheck // wrapped below
+	}); err != nil {
+		return fmt.Errorf("releasing lease: %w", err)
+	}
+
+	return nil
+}
+
+// Add creates a new LogEntry inside the LogStore
+func (l boltLogStore) Add(le *LogEntry) error {
+	if err := l.b.update(func(tx *bolt.Tx) error { return boltPutLogEntry(tx, le) }); err != nil {
+		return fmt.Errorf("storing log entry: %w", err)
+	}
+
+	return nil
+}
+
+// List retrieves unfiltered log entries by page
+func (l boltLogStore) List(num, page int) ([]LogEntry, error) {
+	return l.Query(LogQuery{Num: num, Page: page})
+}
+
+// ListForCatalogEntry retrieves filered log entries by page
+func (l boltLogStore) ListForCatalogEntry(ce *CatalogEntry, num, page int) ([]LogEntry, error) {
+	return l.Query(LogQuery{CatalogEntry: ce, Num: num, Page: page})
+}
+
+// Query retrieves the log entries matching the query, newest first
+func (l boltLogStore) Query(q LogQuery) (out []LogEntry, err error) {
+	out = []LogEntry{}
+
+	if err = l.b.view(func(tx *bolt.Tx) error {
+		var (
+			c    = tx.Bucket(boltBucketLogIndex).Cursor()
+			logs = tx.Bucket(boltBucketLogEntries)
+
+			lower, upper []byte
+		)
+
+		if !q.Since.IsZero() {
+			lower = boltLogIndexKey(q.Since, 0)
+		}
+
+		if !q.Until.IsZero() {
+			upper = boltLogIndexKey(q.Until, 0)
+		}
+
+		// collect adds the entry at the index key and reports whether
+		// enough entries were collected
+		matches := boltLogMatcher(q)
+		skip := q.Num * q.Page
+		collect := func(k []byte) (bool, error) {
+			le, err := decodeBoltLogEntry(k[8:], logs.Get(k[8:]))
+			if err != nil || !matches(le) {
+				return false, err
+			}
+
+			if skip > 0 {
+				skip--
+				return false, nil
+			}
+
+			out = append(out, le)
+			return q.Num > 0 && len(out) >= q.Num, nil
+		}
+
+		switch {
+		case q.After > 0:
+			cursor, err := l.cursorKey(logs, q.After)
+			if err != nil {
+				return err
+			}
+
+			// Fetch the entries directly following the cursor and reverse
+			// them afterwards to keep the newest-first order
+			skip = 0
+			for k, _ := c.Seek(cursor); k != nil && (upper == nil || bytes.Compare(k, upper) < 0); k, _ = c.Next() {
+				if bytes.Equal(k, cursor) || (lower != nil && bytes.Compare(k, lower) < 0) {
+					continue
+				}
+				if done, err := collect(k); err != nil || done {
+					return err
+				}
+			}
+			return nil
+
+		case q.Before > 0:
+			cursor, err := l.cursorKey(logs, q.Before)
+			if err != nil {
+				return err
+			}
+
+			skip = 0
+			if upper == nil || bytes.Compare(cursor, upper) < 0 {
+				upper = cursor
+			}
+		}
+
+		for k := boltSeekBefore(c, upper); k != nil && (lower == nil || bytes.Compare(k, lower) >= 0); k, _ = c.Prev() {
+			if done, err := collect(k); err != nil || done {
+				return err
+			}
+		}
+		return nil
+	}); err != nil {
+		return nil, fmt.Errorf("fetching log entries: %w", err)
+	}
+
+	if q.After > 0 {
+		slices.Reverse(out)
+	}
+
+	return out, nil
+}
+
+// cursorKey retrieves the index key of the log entry with the given ID
+// to be used as a cursor, ErrUnknownCursor is returned when it does
+// not exist
+func (boltLogStore) cursorKey(logs *bolt.Bucket, id uint64) ([]byte, error) {
+	v := logs.Get(boltID(id))
+	if v == nil {
+		return nil, ErrUnknownCursor
+	}
+
+	le, err := decodeBoltLogEntry(boltID(id), v)
+	if err != nil {
+		return nil, err
+	}
+
+	return boltLogIndexKey(le.Timestamp, le.ID), nil
+}
+
+// boltLogMatcher creates a function reporting whether the log entry
+// belongs to the catalog entries selected by the query
+func boltLogMatcher(q LogQuery) func(LogEntry) bool {
+	var entries map[catalogKey]bool
+	if q.CatalogEntries != nil {
+		entries = make(map[catalogKey]bool, len(q.CatalogEntries))
+		for _, ce := range q.CatalogEntries {
+			entries[catalogKey{ce.Name, ce.Tag}] = true
+		}
+	}
+
+	return func(le LogEntry) bool {
+		if q.CatalogEntry != nil && (le.CatalogName != q.CatalogEntry.Name || le.CatalogTag != q.CatalogEntry.Tag) {
+			return false
+		}
+
+		return entries == nil || entries[catalogKey{le.CatalogName, le.CatalogTag}]
+	}
+}
+
+// boltSeekBefore positions the cursor at the last key before the given
+// key or at the last key if no key is given
+func boltSeekBefore(c *bolt.Cursor, key []byte) []byte {
+	if key == nil {
+		k, _ := c.Last()
+		return k
+	}
+
+	if k, _ := c.Seek(key); k == nil {
+		k, _ = c.Last()
+		return k
+	}
+
+	k, _ := c.Prev()
+	return k
+}
+
+// paginate returns the given page of the entries
+func paginate[T any](entries []T, num, page int) []T {
+	start := min(num*page, len(entries))
+	end := min(start+num, len(entries))
+
+	return entries[start:end]
+}
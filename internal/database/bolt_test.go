@@ -0,0 +1,342 @@
+package database
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func newTestBoltClient(t *testing.T) *Client {
+	t.Helper()
+
+	dbc, err := NewClient("bolt", filepath.Join(t.TempDir(), "latestver.db"))
+	if err != nil {
+		t.Fatalf("unable to create database client: %s", err)
+	}
+	t.Cleanup(func() { _ = dbc.Close() })
+
+	return dbc
+}
+
+func Test_BoltCatalogMetaStorage(t *testing.T) {
+	dbc := newTestBoltClient(t)
+
+	var (
+		ce  = CatalogEntry{Name: "testapp", Tag: "latest"}
+		now = time.Now()
+	)
+
+	fetchedCM, err := dbc.Catalog.GetMeta(&ce)
+	if err != nil {
+		t.Fatalf("unable to retrieve catalog meta: %s", err)
+	}
+
+	if fetchedCM.CatalogName != ce.Name || fetchedCM.CatalogTag != ce.Tag || fetchedCM.LastChecked != nil {
+		t.Errorf("expected empty meta, got %+v", fetchedCM)
+	}
+
+	for _, v := range []string{"1.0.0", "1.1.0"} {
+		if err = dbc.Catalog.PutMeta(&CatalogMeta{CatalogName: ce.Name, CatalogTag: ce.Tag, CurrentVersion: v, LastChecked: &now}); err != nil {
+			t.Fatalf("unable to store catalog meta: %s", err)
+		}
+	}
+
+	if fetchedCM, err = dbc.Catalog.GetMeta(&ce); err != nil {
+		t.Fatalf("unable to retrieve catalog meta: %s", err)
+	}
+
+	if fetchedCM.CatalogName != ce.Name || fetchedCM.CurrentVersion != "1.1.0" || !fetchedCM.LastChecked.Equal(now) {
+		t.Errorf("unexpected catalog meta: %+v", fetchedCM)
+	}
+
+	if err = dbc.Catalog.PutMeta(&CatalogMeta{CatalogName: ce.Name, CatalogTag: "beta"}); err != nil {
+		t.Fatalf("unable to store catalog meta: %s", err)
+	}
+
+	metas, err := dbc.Catalog.ListMeta()
+	if err != nil {
+		t.Fatalf("unable to list catalog meta: %s", err)
+	}
+
+	if len(metas) != 2 || metas[0].CatalogTag != "beta" || metas[1].CurrentVersion != "1.1.0" {
+		t.Errorf("unexpected catalog meta list: %+v", metas)
+	}
+}
+
+func Test_BoltChecksAndLeases(t *testing.T) {
+	dbc := newTestBoltClient(t)
+
+	var (
+		ce = CatalogEntry{Name: "testapp", Tag: "latest"}
+		rt = time.Now()
+	)
+
+	for _, ca := range []*CheckAttempt{
+		{CatalogName: ce.Name, CatalogTag: ce.Tag, Started: rt.Add(-2 * time.Hour), Outcome: CheckOutcomeUpdated},
+		{CatalogName: ce.Name, CatalogTag: ce.Tag, Started: rt.Add(-1 * time.Hour), Outcome: CheckOutcomeUnchanged},
+		{CatalogName: ce.Name, CatalogTag: ce.Tag, Started: rt.Add(-3 * time.Hour), Outcome: CheckOutcomeError},
+		{CatalogName: "anotherapp", CatalogTag: ce.Tag, Started: rt, Outcome: CheckOutcomeUpdated},
+	} {
+		if err := dbc.Checks.Add(ca); err != nil {
+			t.Fatalf("unable to add check attempt: %s", err)
+		}
+	}
+
+	checks, err := dbc.Checks.ListForCatalogEntry(&ce, 2, 0)
+	if err != nil {
+		t.Fatalf("unable to list check attempts: %s", err)
+	}
+
+	if len(checks) != 2 || checks[0].ID != 2 || checks[1].ID != 1 {
+		t.Errorf("unexpected first page of check attempts: %+v", checks)
+	}
+
+	if checks, err = dbc.Checks.ListForCatalogEntry(&ce, 2, 1); err != nil || len(checks) != 1 || checks[0].ID != 3 {
+		t.Errorf("unexpected second page of check attempts: %+v (%v)", checks, err)
+	}
+
+	for _, step := range []struct {
+		holder string
+		expect bool
+	}{
+		{"a", true},
+		{"b", false},
+		{"a", true},
+	} {
+		acquired, err := dbc.Leases.Acquire("scheduler", step.holder, time.Minute)
+		if err != nil {
+			t.Fatalf("acquiring lease: %s", err)
+		}
+
+		if acquired != step.expect {
+			t.Errorf("expected acquire by %s to return %v", step.holder, step.expect)
+		}
+	}
+
+	if err = dbc.Leases.Release("scheduler", "a"); err != nil {
+		t.Fatalf("releasing lease: %s", err)
+	}
+
+	if acquired, err := dbc.Leases.Acquire("scheduler", "b", time.Minute); err != nil || !acquired {
+		t.Errorf("expected released lease to be acquired (%v)", err)
+	}
+}
+
+func Test_BoltLogQuery(t *testing.T) {
+	dbc := newTestBoltClient(t)
+
+	var (
+		ce = CatalogEntry{Name: "testapp", Tag: "latest"}
+		rt = time.Now().UTC().Truncate(time.Hour)
+	)
+
+	for _, ts := range []time.Time{rt.Add(-4 * time.Hour), rt.Add(-3 * time.Hour), rt.Add(-3 * time.Hour), rt.Add(-2 * time.Hour), rt.Add(-1 * time.Hour)} {
+		if err := dbc.Logs.Add(&LogEntry{CatalogName: ce.Name, CatalogTag: ce.Tag, Timestamp: ts}); err != nil {
+			t.Fatalf("unable to add log entry: %s", err)
+		}
+	}
+
+	if err := dbc.Logs.Add(&LogEntry{CatalogName: "anotherapp", CatalogTag: ce.Tag, Timestamp: rt.Add(-30 * time.Minute)}); err != nil {
+		t.Fatalf("unable to add log entry: %s", err)
+	}
+
+	ids := func(logs []LogEntry) (out []uint64) {
+		for _, le := range logs {
+			out = append(out, le.ID)
+		}
+		return out
+	}
+
+	for name, tc := range map[string]struct {
+		query  LogQuery
+		expect []uint64
+	}{
+		"first page":       {LogQuery{CatalogEntry: &ce, Num: 2}, []uint64{5, 4}},
+		"second page":      {LogQuery{CatalogEntry: &ce, Num: 2, Page: 1}, []uint64{3, 2}},
+		"all entries":      {LogQuery{Num: 10}, []uint64{6, 5, 4, 3, 2, 1}},
+		"before cursor":    {LogQuery{Num: 2, Before: 4}, []uint64{3, 2}},
+		"before shared ts": {LogQuery{Num: 2, Before: 3}, []uint64{2, 1}},
+		"after cursor":     {LogQuery{Num: 2, After: 2}, []uint64{4, 3}},
+		"after shared ts":  {LogQuery{Num: 2, After: 1}, []uint64{3, 2}},
+		"since":            {LogQuery{CatalogEntry: &ce, Num: 10, Since: rt.Add(-3 * time.Hour)}, []uint64{5, 4, 3, 2}},
+		"until":            {LogQuery{Num: 10, Until: rt.Add(-2 * time.Hour)}, []uint64{3, 2, 1}},
+		"entry and range":  {LogQuery{CatalogEntry: &ce, Num: 10, Since: rt.Add(-2 * time.Hour), Until: rt}, []uint64{5, 4}},
+		"entry set":        {LogQuery{CatalogEntries: []CatalogEntry{{Name: "anotherapp", Tag: ce.Tag}, ce}, Num: 2}, []uint64{6, 5}},
+		"empty entry set":  {LogQuery{CatalogEntries: []CatalogEntry{}, Num: 2}, nil},
+	} {
+		logs, err := dbc.Logs.Query(tc.query)
+		if err != nil {
+			t.Fatalf("%s: querying log: %s", name, err)
+		}
+
+		if got := ids(logs); !slices.Equal(got, tc.expect) {
+			t.Errorf("%s: expected IDs %v, got %v", name, tc.expect, got)
+		}
+	}
+
+	if _, err := dbc.Logs.Query(LogQuery{Num: 2, Before: 100}); !errors.Is(err, ErrUnknownCursor) {
+		t.Errorf("expected ErrUnknownCursor for missing cursor, got %v", err)
+	}
+}
+
+func Test_BoltPrune(t *testing.T) {
+	dbc := newTestBoltClient(t)
+
+	var (
+		ce  = CatalogEntry{Name: "testapp", Tag: "latest"}
+		old = CatalogEntry{Name: "removedapp", Tag: "latest"}
+		rt  = time.Now()
+	)
+
+	for _, e := range []CatalogEntry{ce, old} {
+		if err := dbc.Catalog.PutMeta(&CatalogMeta{CatalogName: e.Name, CatalogTag: e.Tag, CurrentVersion: "1.0.0"}); err != nil {
+			t.Fatalf("unable to store catalog meta: %s", err)
+		}
+
+		for i := range 3 {
+			ts := rt.Add(-time.Duration(i) * 24 * time.Hour)
+			if err := dbc.Logs.Add(&LogEntry{CatalogName: e.Name, CatalogTag: e.Tag, Timestamp: ts}); err != nil {
+				t.Fatalf("unable to add log entry: %s", err)
+			}
+
+			if err := dbc.Checks.Add(&CheckAttempt{CatalogName: e.Name, CatalogTag: e.Tag, Started: ts}); err != nil {
+				t.Fatalf("unable to add check attempt: %s", err)
+			}
+		}
+	}
+
+	res, err := dbc.Prune(PruneOptions{
+		Catalog:       []CatalogEntry{ce},
+		CheckMaxAge:   36 * time.Hour,
+		LogMaxEntries: 2,
+		RemoveOrphans: true,
+	})
+	if err != nil {
+		t.Fatalf("pruning: %s", err)
+	}
+
+	if res.Metas != 1 || res.Checks != 4 || res.Logs != 4 {
+		t.Errorf("unexpected prune result: %+v", res)
+	}
+
+	logs, err := dbc.Logs.List(100, 0)
+	if err != nil {
+		t.Fatalf("unable to list log entries: %s", err)
+	}
+
+	if len(logs) != 2 || logs[0].CatalogName != ce.Name {
+		t.Errorf("unexpected log entries after prune: %+v", logs)
+	}
+}
+
+func Test_BoltTransactionRollback(t *testing.T) {
+	dbc := newTestBoltClient(t)
+
+	errRollback := errors.New("rollback")
+	if err := dbc.Transaction(func(tx *Client) error {
+		if err := tx.Logs.Add(&LogEntry{CatalogName: "testapp", CatalogTag: "latest", Timestamp: time.Now()}); err != nil {
+			return err
+		}
+		return errRollback
+	}); !errors.Is(err, errRollback) {
+		t.Fatalf("expected rollback error, got %v", err)
+	}
+
+	if logs, err := dbc.Logs.List(100, 0); err != nil || len(logs) != 0 {
+		t.Errorf("expected no log entries after rollback, got %d (%v)", len(logs), err)
+	}
+}
+
+func Test_BoltMigrateAndBackup(t *testing.T) {
+	src, err := NewClient("sqlite3", "file:boltmigratesrc?mode=memory&cache=shared")
+	if err != nil {
+		t.Fatalf("unable to create source client: %s", err)
+	}
+	t.Cleanup(func() { _ = src.Close() })
+
+	now := time.Now()
+	for _, name := range []string{"app0", "app1", "app2"} {
+		if err = src.Catalog.PutMeta(&CatalogMeta{CatalogName: name, CatalogTag: "latest", CurrentVersion: "1.0.0", LastChecked: &now}); err != nil {
+			t.Fatalf("adding meta: %s", err)
+		}
+
+		if err = src.Logs.Add(&LogEntry{CatalogName: name, CatalogTag: "latest", Timestamp: now, VersionTo: "1.0.0"}); err != nil {
+			t.Fatalf("adding log entry: %s", err)
+		}
+
+		if err = src.Checks.Add(&CheckAttempt{CatalogName: name, CatalogTag: "latest", Started: now, Outcome: CheckOutcomeUpdated}); err != nil {
+			t.Fatalf("adding check attempt: %s", err)
+		}
+	}
+
+	dest := newTestBoltClient(t)
+
+	// Migrating twice must not duplicate any rows
+	for range 2 {
+		if _, err = src.Migrate(dest, MigrateOptions{BatchSize: 2}); err != nil {
+			t.Fatalf("migrating: %s", err)
+		}
+	}
+
+	res, err := src.Verify(dest, 2)
+	if err != nil {
+		t.Fatalf("verifying: %s", err)
+	}
+
+	for _, r := range res {
+		if r.DestRows != 3 || !r.Match() {
+			t.Errorf("expected %s to match, got %+v", r.Table, r)
+		}
+	}
+
+	// Re-importing our own backup must skip all records
+	var buf bytes.Buffer
+	if _, err = dest.Export(&buf); err != nil {
+		t.Fatalf("exporting: %s", err)
+	}
+
+	imported, err := dest.Import(&buf)
+	if err != nil {
+		t.Fatalf("importing: %s", err)
+	}
+
+	if imported.LogEntries != 0 || imported.Skipped != 3 {
+		t.Errorf("unexpected import result: %+v", imported)
+	}
+}
+
+func Test_BoltEachBatches(t *testing.T) {
+	dbc := newTestBoltClient(t)
+	db := dbc.backend.(boltBackend).db //nolint:forcetypeassert // test client is always bolt
+
+	for i := range 5 {
+		if err := dbc.Catalog.PutMeta(&CatalogMeta{CatalogName: fmt.Sprintf("app%d", i), CatalogTag: "latest"}); err != nil {
+			t.Fatalf("adding meta: %s", err)
+		}
+	}
+
+	var names []string
+	if err := dbc.backend.eachCatalogMeta(2, func(batch []CatalogMeta) error {
+		// The batch is passed on after closing the read transaction
+		if n := db.Stats().OpenTxN; n != 0 {
+			t.Errorf("expected no open read transaction, got %d", n)
+		}
+
+		for _, cm := range batch {
+			names = append(names, cm.CatalogName)
+		}
+
+		// Writing while iterating must not affect the following batches
+		return dbc.Catalog.PutMeta(&CatalogMeta{CatalogName: batch[0].CatalogName, CatalogTag: "latest", CurrentVersion: "1.0.0"})
+	}); err != nil {
+		t.Fatalf("reading catalog meta: %s", err)
+	}
+
+	if expect := []string{"app0", "app1", "app2", "app3", "app4"}; !slices.Equal(names, expect) {
+		t.Errorf("expected %v, got %v", expect, names)
+	}
+}
@@ -0,0 +1,67 @@
+package database
+
+import (
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Outcomes of a CheckAttempt
+const (
+	CheckOutcomeBlocked   CheckOutcome = "blocked"
+	CheckOutcomeError     CheckOutcome = "error"
+	CheckOutcomeUnchanged CheckOutcome = "unchanged"
+	CheckOutcomeUpdated   CheckOutcome = "updated"
+)
+
+type (
+	// CheckAttempt represents a single check executed for a catalog entry
+	CheckAttempt struct {
+		ID          uint64    `gorm:"primaryKey" json:"-"`
+		CatalogName string    `gorm:"index:check_catalog_key" json:"catalog_name"`
+		CatalogTag  string    `gorm:"index:check_catalog_key" json:"catalog_tag"`
+		Started     time.Time `gorm:"index:,sort:desc" json:"started"`
+		Finished    time.Time `json:"finished"`
+		DurationMS  int64     `json:"duration_ms"`
+		// FetchedVersion is the version as reported by the upstream
+		// source, SelectedVersion the one compared against the current
+		// version after applying transform and version constraint
+		FetchedVersion  string       `json:"fetched_version,omitempty"`
+		SelectedVersion string       `json:"selected_version,omitempty"`
+		Outcome         CheckOutcome `json:"outcome"`
+		Error           string       `json:"error,omitempty"`
+	}
+
+	// CheckOutcome describes the result of a CheckAttempt
+	CheckOutcome string
+
+	// gormCheckStore implements the CheckStore for SQL databases
+	gormCheckStore struct {
+		db *gorm.DB
+	}
+)
+
+// Add creates a new CheckAttempt inside the CheckStore
+func (c gormCheckStore) Add(ca *CheckAttempt) error {
+	if err := c.db.Create(ca).Error; err != nil {
+		return fmt.Errorf("writing check attempt: %w", err)
+	}
+
+	return nil
+}
+
+// ListForCatalogEntry retrieves the check attempts of the catalog
+// entry by page, latest attempts first
+func (c gormCheckStore) ListForCatalogEntry(ce *CatalogEntry, num, page int) (out []CheckAttempt, err error) {
+	if err = c.db.
+		Where(&CheckAttempt{CatalogName: ce.Name, CatalogTag: ce.Tag}).
+		Order("started desc").
+		Limit(num).Offset(num * page).
+		Find(&out).
+		Error; err != nil {
+		return nil, fmt.Errorf("fetching check attempts: %w", err)
+	}
+
+	return out, nil
+}
@@ -0,0 +1,51 @@
+package database
+
+import (
+	"testing"
+	"time"
+)
+
+func Test_CheckStorage(t *testing.T) {
+	dbc, err := NewClient("sqlite3", sqlliteMemoryDSN)
+	if err != nil {
+		t.Fatalf("unable to create database client: %s", err)
+	}
+
+	var (
+		ce    = CatalogEntry{Name: "checkapp", Tag: "latest"}
+		other = CatalogEntry{Name: "checkapp", Tag: "beta"}
+		start = time.Now().UTC()
+	)
+
+	for i, outcome := range []CheckOutcome{CheckOutcomeUpdated, CheckOutcomeUnchanged, CheckOutcomeError} {
+		if err = dbc.Checks.Add(&CheckAttempt{
+			CatalogName: ce.Name,
+			CatalogTag:  ce.Tag,
+			Started:     start.Add(time.Duration(i) * time.Minute),
+			Outcome:     outcome,
+		}); err != nil {
+			t.Fatalf("adding check attempt: %s", err)
+		}
+	}
+
+	if err = dbc.Checks.Add(&CheckAttempt{CatalogName: other.Name, CatalogTag: other.Tag, Started: start, Outcome: CheckOutcomeBlocked}); err != nil {
+		t.Fatalf("adding check attempt: %s", err)
+	}
+
+	checks, err := dbc.Checks.ListForCatalogEntry(&ce, 2, 0)
+	if err != nil {
+		t.Fatalf("listing check attempts: %s", err)
+	}
+
+	if len(checks) != 2 {
+		t.Fatalf("expected 2 check attempts, got %d", len(checks))
+	}
+
+	if checks[0].Outcome != CheckOutcomeError || checks[1].Outcome != CheckOutcomeUnchanged {
+		t.Errorf("unexpected order of check attempts: %s, %s", checks[0].Outcome, checks[1].Outcome)
+	}
+
+	if checks, _ = dbc.Checks.ListForCatalogEntry(&other, 10, 0); len(checks) != 1 {
+		t.Errorf("expected 1 check attempt for other entry, got %d", len(checks))
+	}
+}
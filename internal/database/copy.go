@@ -0,0 +1,316 @@
+package database
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// DefaultMigrateBatchSize is used when no BatchSize is given in the
+// MigrateOptions or to Client.Verify
+const DefaultMigrateBatchSize = 1000
+
+type (
+	// MigrateOptions configures how data is copied in Client.Migrate
+	MigrateOptions struct {
+		// BatchSize limits the number of rows read and written at once
+		BatchSize int
+		// DryRun only counts the rows in the source database, the dest
+		// database is not used so rows already present there are
+		// counted too
+		DryRun bool
+	}
+
+	// MigrateResult contains the number of rows copied (or found in the
+	// source database in a dry-run) for one table
+	MigrateResult struct {
+		Table string
+		Rows  int64
+		// Skipped contains the number of rows already present in the
+		// dest database
+		Skipped int64
+	}
+
+	// VerifyResult contains row counts and checksums of one table in
+	// the source and the destination database
+	VerifyResult struct {
+		Table string
+
+		SourceRows     int64
+		SourceChecksum string
+
+		DestRows     int64
+		DestChecksum string
+	}
+)
+
+// Match reports whether source and destination contain the same data
+func (v VerifyResult) Match() bool {
+	return v.SourceRows == v.DestRows && v.SourceChecksum == v.DestChecksum
+}
+
+// Migrate copies the data of all required types into the dest
+// database which may use a different storage backend. Catalog meta is
+// updated by its catalog entry, check attempts and log entries already
+// present (compared ignoring their IDs) are skipped and the others are
+// inserted using new IDs. This keeps existing data in the dest
+// database and allows to repeat the migration, for example after a
+// partial failure. In a dry-run the dest database is not used and may
+// be nil.
+func (c Client) Migrate(dest *Client, opts MigrateOptions) (res []MigrateResult, err error) {
+	var destBackend backend
+	if !opts.DryRun {
+		destBackend = dest.backend
+	}
+
+	for _, fn := range []func() (MigrateResult, error){
+		func() (MigrateResult, error) {
+			return copyRows("catalog_meta", c.backend.eachCatalogMeta, writeFunc(destBackend, backend.upsertCatalogMetas), opts)
+		},
+		func() (MigrateResult, error) {
+			return copyRows("check_attempts", c.backend.eachCheckAttempt, writeFunc(destBackend, backend.insertCheckAttempts), opts)
+		},
+		func() (MigrateResult, error) {
+			return copyRows("log_entries", c.backend.eachLogEntry, writeFunc(destBackend, backend.insertLogEntries), opts)
+		},
+	} {
+		var r MigrateResult
+		if r, err = fn(); err != nil {
+			return res, fmt.Errorf("executing migration: %w", err)
+		}
+		res = append(res, r)
+	}
+
+	return res, nil
+}
+
+// Verify compares row counts and checksums of all required types
+// with the dest database
+func (c Client) Verify(dest *Client, batchSize int) (res []VerifyResult, err error) {
+	for _, fn := range []func() (VerifyResult, error){
+		func() (VerifyResult, error) {
+			return verifyRows("catalog_meta", c.backend.eachCatalogMeta, dest.backend.eachCatalogMeta, batchSize)
+		},
+		func() (VerifyResult, error) {
+			return verifyRows("check_attempts", c.backend.eachCheckAttempt, dest.backend.eachCheckAttempt, batchSize)
+		},
+		func() (VerifyResult, error) {
+			return verifyRows("log_entries", c.backend.eachLogEntry, dest.backend.eachLogEntry, batchSize)
+		},
+	} {
+		var r VerifyResult
+		if r, err = fn(); err != nil {
+			return res, fmt.Errorf("executing verification: %w", err)
+		}
+		res = append(res, r)
+	}
+
+	return res, nil
+}
+
+// checksumRows calculates an order-independent checksum over all
+// rows: databases differ in their collations so the rows are not
+// guaranteed to be returned in the same order
+func checksumRows[T any](each func(int, func([]T) error) error, batchSize int) (rows int64, checksum string, err error) {
+	sch, err := schema.Parse(new(T), &sync.Map{}, schema.NamingStrategy{})
+	if err != nil {
+		return 0, "", fmt.Errorf("parsing schema: %w", err)
+	}
+
+	var sum uint64
+	if err = each(batchSize, func(batch []T) error {
+		for i := range batch {
+			sum += rowChecksum(sch, reflect.ValueOf(&batch[i]).Elem())
+		}
+		rows += int64(len(batch))
+		return nil
+	}); err != nil {
+		return 0, "", err
+	}
+
+	return rows, fmt.Sprintf("%016x", sum), nil
+}
+
+// copyRows reads all rows using the each function and writes them
+// using the write function which must skip rows already present so
+// copying the data again does not duplicate them. It returns the
+// number of rows written. In a dry-run write is nil.
+func copyRows[T any](table string, each func(int, func([]T) error) error, write func([]T) (int64, error), opts MigrateOptions) (MigrateResult, error) {
+	res := MigrateResult{Table: table}
+
+	if err := each(opts.BatchSize, func(batch []T) error {
+		if opts.DryRun {
+			res.Rows += int64(len(batch))
+			return nil
+		}
+
+		n, err := write(batch)
+		if err != nil {
+			return fmt.Errorf("writing rows: %w", err)
+		}
+
+		res.Rows += n
+		res.Skipped += int64(len(batch)) - n
+		return nil
+	}); err != nil {
+		return res, fmt.Errorf("copying %s: %w", table, err)
+	}
+
+	return res, nil
+}
+
+// eachBatch reads the whole table in batches ordered by primary key
+func eachBatch[T any](db *gorm.DB, batchSize int, fn func(batch []T) error) error {
+	if batchSize <= 0 {
+		batchSize = DefaultMigrateBatchSize
+	}
+
+	stmt := &gorm.Statement{DB: db}
+	if err := stmt.Parse(new(T)); err != nil {
+		return fmt.Errorf("parsing schema: %w", err)
+	}
+
+	var batch []T
+
+	if stmt.Schema.PrioritizedPrimaryField != nil {
+		// Single primary key: gorm uses it as cursor for the batches
+		if err := db.FindInBatches(&batch, batchSize, func(*gorm.DB, int) error {
+			return fn(batch)
+		}).Error; err != nil {
+			return fmt.Errorf("reading batches: %w", err)
+		}
+
+		return nil
+	}
+
+	// Composite primary keys are not supported by FindInBatches
+	order := strings.Join(stmt.Schema.PrimaryFieldDBNames, ", ")
+	for offset := 0; ; offset += batchSize {
+		if err := db.Order(order).Limit(batchSize).Offset(offset).Find(&batch).Error; err != nil {
+			return fmt.Errorf("reading batch: %w", err)
+		}
+
+		if len(batch) == 0 {
+			return nil
+		}
+
+		if err := fn(batch); err != nil {
+			return err
+		}
+
+		if len(batch) < batchSize {
+			return nil
+		}
+	}
+}
+
+// insertNewRows inserts the rows not reported as existing by the has
+// function within a transaction, the ID of the new rows is reset
+// using resetID to let the database assign a new one
+func insertNewRows[T any](db *gorm.DB, rows []T, has func(gormBackend, T) (bool, error), resetID func(*T)) (inserted int64, err error) {
+	err = db.Transaction(func(tx *gorm.DB) error {
+		var insert []T
+		for _, row := range rows {
+			exists, err := has(gormBackend{tx}, row)
+			if err != nil {
+				return err
+			}
+
+			if !exists {
+				resetID(&row)
+				insert = append(insert, row)
+			}
+		}
+
+		if len(insert) == 0 {
+			return nil
+		}
+
+		if err := tx.Create(&insert).Error; err != nil {
+			return fmt.Errorf("inserting rows: %w", err)
+		}
+
+		inserted = int64(len(insert))
+		return nil
+	})
+
+	return inserted, err //nolint:wrapcheck // errors are wrapped inside the transaction
+}
+
+// rowChecksum hashes all columns of the row except auto-incremented
+// IDs which are not kept by the migration. Times are normalized to UTC
+// with millisecond precision as databases store them differently.
+func rowChecksum(sch *schema.Schema, row reflect.Value) uint64 {
+	h := sha256.New()
+
+	for _, f := range sch.Fields {
+		if f.DBName == "" || (f.PrimaryKey && f.AutoIncrement) {
+			continue
+		}
+
+		v, _ := f.ValueOf(context.Background(), row)
+		switch t := v.(type) {
+		case time.Time:
+			v = t.UTC().Truncate(time.Millisecond).Format(time.RFC3339Nano)
+
+		case *time.Time:
+			v = nil
+			if t != nil {
+				v = t.UTC().Truncate(time.Millisecond).Format(time.RFC3339Nano)
+			}
+		}
+
+		fmt.Fprintf(h, "%s=%v\x00", f.DBName, v)
+	}
+
+	return binary.BigEndian.Uint64(h.Sum(nil))
+}
+
+// upsertRows creates or updates the rows in the database keeping
+// their primary keys
+func upsertRows[T any](db *gorm.DB, rows []T) (int64, error) {
+	if len(rows) == 0 {
+		return 0, nil
+	}
+
+	if err := db.Clauses(clause.OnConflict{UpdateAll: true}).Create(&rows).Error; err != nil {
+		return 0, fmt.Errorf("upserting rows: %w", err)
+	}
+
+	return int64(len(rows)), nil
+}
+
+// verifyRows compares row count and checksum of the rows in the
+// source and destination database
+func verifyRows[T any](table string, src, dest func(int, func([]T) error) error, batchSize int) (res VerifyResult, err error) {
+	res.Table = table
+
+	if res.SourceRows, res.SourceChecksum, err = checksumRows(src, batchSize); err != nil {
+		return res, fmt.Errorf("checksumming source: %w", err)
+	}
+
+	if res.DestRows, res.DestChecksum, err = checksumRows(dest, batchSize); err != nil {
+		return res, fmt.Errorf("checksumming dest: %w", err)
+	}
+
+	return res, nil
+}
+
+// writeFunc binds the write method to the backend, nil is returned
+// for a nil backend (in a dry-run)
+func writeFunc[T any](b backend, write func(backend, []T) (int64, error)) func([]T) (int64, error) {
+	if b == nil {
+		return nil
+	}
+
+	return func(rows []T) (int64, error) { return write(b, rows) }
+}
@@ -0,0 +1,150 @@
+package database
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func Test_MigrateAndVerify(t *testing.T) {
+	src, err := NewClient("sqlite3", "file:copysrc?mode=memory&cache=shared")
+	if err != nil {
+		t.Fatalf("unable to create source client: %s", err)
+	}
+	t.Cleanup(func() { _ = src.Close() })
+
+	dest, err := NewClient("sqlite3", "file:copydest?mode=memory&cache=shared")
+	if err != nil {
+		t.Fatalf("unable to create dest client: %s", err)
+	}
+	t.Cleanup(func() { _ = dest.Close() })
+
+	now := time.Now()
+	for i := range 5 {
+		ce := CatalogEntry{Name: fmt.Sprintf("app%d", i), Tag: "latest"}
+
+		if err = src.Catalog.PutMeta(&CatalogMeta{CatalogName: ce.Name, CatalogTag: ce.Tag, CurrentVersion: "1.0.0", LastChecked: &now}); err != nil {
+			t.Fatalf("adding meta: %s", err)
+		}
+
+		if err = src.Logs.Add(&LogEntry{CatalogName: ce.Name, CatalogTag: ce.Tag, Timestamp: now, VersionTo: "1.0.0"}); err != nil {
+			t.Fatalf("adding log entry: %s", err)
+		}
+
+		if err = src.Checks.Add(&CheckAttempt{CatalogName: ce.Name, CatalogTag: ce.Tag, Started: now, Outcome: CheckOutcomeUpdated}); err != nil {
+			t.Fatalf("adding check attempt: %s", err)
+		}
+	}
+
+	dry, err := src.Migrate(nil, MigrateOptions{DryRun: true})
+	if err != nil {
+		t.Fatalf("dry-run: %s", err)
+	}
+
+	for _, r := range dry {
+		if r.Rows != 5 {
+			t.Errorf("dry-run: expected 5 rows for %s, got %d", r.Table, r.Rows)
+		}
+	}
+
+	if res, _ := src.Verify(dest, 2); res[0].Match() {
+		t.Error("dry-run must not copy any data")
+	}
+
+	// Migrating twice must not duplicate any rows
+	for range 2 {
+		if _, err = src.Migrate(dest, MigrateOptions{BatchSize: 2}); err != nil {
+			t.Fatalf("migrating: %s", err)
+		}
+	}
+
+	res, err := src.Verify(dest, 2)
+	if err != nil {
+		t.Fatalf("verifying: %s", err)
+	}
+
+	for _, r := range res {
+		if r.DestRows != 5 || !r.Match() {
+			t.Errorf("expected %s to match, got %+v", r.Table, r)
+		}
+	}
+
+	// New entries must not collide with the copied ones
+	if err = dest.Logs.Add(&LogEntry{CatalogName: "app0", CatalogTag: "latest", Timestamp: now, VersionTo: "1.1.0"}); err != nil {
+		t.Fatalf("adding log entry to dest: %s", err)
+	}
+
+	res, err = src.Verify(dest, 2)
+	if err != nil {
+		t.Fatalf("verifying: %s", err)
+	}
+
+	for _, r := range res {
+		if r.Table == "log_entries" && r.Match() {
+			t.Error("expected log entries to differ")
+		}
+	}
+}
+
+func Test_MigrateKeepsExistingData(t *testing.T) {
+	now := time.Now()
+
+	addEntries := func(c *Client, tag string) {
+		if err := c.Logs.Add(&LogEntry{CatalogName: "app", CatalogTag: tag, Timestamp: now, VersionTo: "1.0.0"}); err != nil {
+			t.Fatalf("adding log entry: %s", err)
+		}
+
+		if err := c.Checks.Add(&CheckAttempt{CatalogName: "app", CatalogTag: tag, Started: now, Outcome: CheckOutcomeUpdated}); err != nil {
+			t.Fatalf("adding check attempt: %s", err)
+		}
+	}
+
+	src, err := NewClient("sqlite3", "file:copykeepsrc?mode=memory&cache=shared")
+	if err != nil {
+		t.Fatalf("unable to create source client: %s", err)
+	}
+	t.Cleanup(func() { _ = src.Close() })
+	addEntries(src, "latest")
+
+	gormDest, err := NewClient("sqlite3", "file:copykeepdest?mode=memory&cache=shared")
+	if err != nil {
+		t.Fatalf("unable to create dest client: %s", err)
+	}
+	t.Cleanup(func() { _ = gormDest.Close() })
+
+	for name, dest := range map[string]*Client{"gorm": gormDest, "bolt": newTestBoltClient(t)} {
+		// The existing rows use the same IDs as the source rows
+		addEntries(dest, "stable")
+
+		for i, expSkipped := range []int64{0, 1} {
+			res, err := src.Migrate(dest, MigrateOptions{})
+			if err != nil {
+				t.Fatalf("%s: migrating: %s", name, err)
+			}
+
+			for _, r := range res[1:] {
+				if r.Rows != 1-expSkipped || r.Skipped != expSkipped {
+					t.Errorf("%s: run %d: unexpected result for %s: %+v", name, i, r.Table, r)
+				}
+			}
+		}
+
+		for _, tag := range []string{"latest", "stable"} {
+			ce := &CatalogEntry{Name: "app", Tag: tag}
+
+			logs, err := dest.Logs.ListForCatalogEntry(ce, 10, 0)
+			if err != nil {
+				t.Fatalf("%s: listing log entries: %s", name, err)
+			}
+
+			checks, err := dest.Checks.ListForCatalogEntry(ce, 10, 0)
+			if err != nil {
+				t.Fatalf("%s: listing check attempts: %s", name, err)
+			}
+
+			if len(logs) != 1 || len(checks) != 1 {
+				t.Errorf("%s: expected one log entry and check attempt for app:%s, got %d / %d", name, tag, len(logs), len(checks))
+			}
+		}
+	}
+}
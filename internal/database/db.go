@@ -19,27 +19,31 @@ type (
 	// Client represents a database client
 	Client struct {
 		Catalog CatalogMetaStore
+		Checks  CheckStore
+		Leases  LeaseStore
 		Logs    LogStore
 
+		backend backend
+	}
+
+	// gormBackend implements the backend for SQL databases
+	gormBackend struct {
 		db *gorm.DB
 	}
 
 	logwrap struct {
 		l *io.PipeWriter
 	}
-
-	migrator interface {
-		Migrate(dest *Client) error
-	}
 )
 
 // NewClient creates a new Client and connects to the database using
 // some default configurations. The database is automatically
 // initialized with required tables.
 func NewClient(dbtype, dsn string) (*Client, error) {
-	c := &Client{}
-	c.Catalog = CatalogMetaStore{c}
-	c.Logs = LogStore{c}
+	var (
+		db  *gorm.DB
+		err error
+	)
 
 	dbLogger := logger.New(
 		&logwrap{log.StandardLogger().WriterLevel(log.TraceLevel)},
@@ -52,71 +56,162 @@ func NewClient(dbtype, dsn string) (*Client, error) {
 	)
 
 	switch dbtype {
+	case "bolt", "bbolt":
+		return newBoltClient(dsn)
+
 	case "mysql":
-		db, err := gorm.Open(mysql.Open(dsn), &gorm.Config{
+		if db, err = gorm.Open(mysql.Open(dsn), &gorm.Config{
 			Logger: dbLogger,
-		})
-		if err != nil {
+		}); err != nil {
 			return nil, fmt.Errorf("opening mysql database: %w", err)
 		}
-		c.db = db
 
 	case "crdb", "postgres", "postgresql":
-		db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{
+		if db, err = gorm.Open(postgres.Open(dsn), &gorm.Config{
 			Logger: dbLogger,
-		})
-		if err != nil {
+		}); err != nil {
 			return nil, fmt.Errorf("opening postgres database: %w", err)
 		}
-		c.db = db
 
 	case "sqlite", "sqlite3":
-		db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{
+		if db, err = gorm.Open(sqlite.Open(dsn), &gorm.Config{
 			Logger: dbLogger,
-		})
-		if err != nil {
+		}); err != nil {
 			return nil, fmt.Errorf("opening sqlite3 database: %w", err)
 		}
-		c.db = db
+
+		// SQLite does not support concurrent writes, especially not
+		// within transactions, so we need to serialize access
+		sqlDB, err := db.DB()
+		if err != nil {
+			return nil, fmt.Errorf("getting sqlite3 connection: %w", err)
+		}
+		sqlDB.SetMaxOpenConns(1)
 
 	default:
 		return nil, fmt.Errorf("invalid db type: %s", dbtype)
 	}
 
-	if err := c.initDB(); err != nil {
+	if err = migrateSchema(db); err != nil {
 		return nil, fmt.Errorf("initializing database: %w", err)
 	}
 
-	return c, nil
+	return newGormClient(db), nil
 }
 
-// Migrate executes database migrations for all required types
-func (c Client) Migrate(dest *Client) error {
-	for _, m := range []migrator{
-		c.Catalog,
-		c.Logs,
-	} {
-		if err := m.Migrate(dest); err != nil {
-			return fmt.Errorf("executing migrating: %w", err)
-		}
+// Close closes the underlying database connection
+func (c Client) Close() error {
+	return c.backend.close()
+}
+
+// Transaction executes the given function within a database
+// transaction: the Client passed to the function must be used for
+// all operations belonging to the transaction. If the function
+// returns an error the transaction is rolled back.
+func (c Client) Transaction(fn func(tx *Client) error) error {
+	if err := c.backend.transaction(fn); err != nil {
+		return fmt.Errorf("executing transaction: %w", err)
 	}
 
 	return nil
 }
 
-func (c Client) initDB() error {
-	for name, fn := range map[string]func() error{
-		"catalogMeta": c.Catalog.ensureTable,
-		"log":         c.Logs.ensureTable,
-	} {
-		if err := fn(); err != nil {
-			return fmt.Errorf("ensuring table %q: %w", name, err)
-		}
+func newGormClient(db *gorm.DB) *Client {
+	return &Client{
+		Catalog: gormCatalogMetaStore{db},
+		Checks:  gormCheckStore{db},
+		Leases:  gormLeaseStore{db},
+		Logs:    gormLogStore{db},
+
+		backend: gormBackend{db},
+	}
+}
+
+func (g gormBackend) close() error {
+	db, err := g.db.DB()
+	if err != nil {
+		return fmt.Errorf("getting database connection: %w", err)
+	}
+
+	if err = db.Close(); err != nil {
+		return fmt.Errorf("closing database connection: %w", err)
 	}
 
 	return nil
 }
 
+func (g gormBackend) eachCatalogMeta(batchSize int, fn func([]CatalogMeta) error) error {
+	return eachBatch(g.db, batchSize, fn)
+}
+
+func (g gormBackend) eachCheckAttempt(batchSize int, fn func([]CheckAttempt) error) error {
+	return eachBatch(g.db, batchSize, fn)
+}
+
+func (g gormBackend) eachLogEntry(batchSize int, fn func([]LogEntry) error) error {
+	return eachBatch(g.db, batchSize, fn)
+}
+
+func (g gormBackend) hasCheckAttempt(ca CheckAttempt) (bool, error) {
+	// Databases store timestamps with different precision so the same
+	// attempt might not have the exact same start time anymore
+	ts := ca.Started.UTC().Truncate(time.Millisecond)
+
+	var count int64
+	if err := g.db.Model(&CheckAttempt{}).
+		Where(map[string]any{
+			"catalog_name": ca.CatalogName,
+			"catalog_tag":  ca.CatalogTag,
+		}).
+		Where("started >= ? AND started < ?", ts, ts.Add(time.Millisecond)).
+		Count(&count).
+		Error; err != nil {
+		return false, fmt.Errorf("counting check attempts: %w", err)
+	}
+
+	return count > 0, nil
+}
+
+func (g gormBackend) hasLogEntry(le LogEntry) (bool, error) {
+	// Databases store timestamps with different precision so the same
+	// entry might not have the exact same timestamp anymore
+	ts := le.Timestamp.UTC().Truncate(time.Millisecond)
+
+	var count int64
+	if err := g.db.Model(&LogEntry{}).
+		Where(map[string]any{
+			"catalog_name": le.CatalogName,
+			"catalog_tag":  le.CatalogTag,
+			"version_from": le.VersionFrom,
+			"version_to":   le.VersionTo,
+		}).
+		Where("timestamp >= ? AND timestamp < ?", ts, ts.Add(time.Millisecond)).
+		Count(&count).
+		Error; err != nil {
+		return false, fmt.Errorf("counting log entries: %w", err)
+	}
+
+	return count > 0, nil
+}
+
+func (g gormBackend) insertCheckAttempts(checks []CheckAttempt) (int64, error) {
+	return insertNewRows(g.db, checks, gormBackend.hasCheckAttempt, func(ca *CheckAttempt) { ca.ID = 0 })
+}
+
+func (g gormBackend) insertLogEntries(logs []LogEntry) (int64, error) {
+	return insertNewRows(g.db, logs, gormBackend.hasLogEntry, func(le *LogEntry) { le.ID = 0 })
+}
+
+func (g gormBackend) transaction(fn func(tx *Client) error) error {
+	return g.db.Transaction(func(db *gorm.DB) error { //nolint:wrapcheck // wrapped by Client.Transaction
+		return fn(newGormClient(db))
+	})
+}
+
+func (g gormBackend) upsertCatalogMetas(metas []CatalogMeta) (int64, error) {
+	return upsertRows(g.db, metas)
+}
+
 func (l logwrap) Printf(f string, v ...any) {
 	fmt.Fprintf(l.l, f, v...) //nolint:errcheck // only logging
 }
@@ -1,6 +1,10 @@
 package database
 
-import "testing"
+import (
+	"errors"
+	"testing"
+	"time"
+)
 
 func Test_CreateInvalidDatabase(t *testing.T) {
 	_, err := NewClient("invalid", "")
@@ -22,3 +26,52 @@ func Test_CreateInaccessibleMYSQL(t *testing.T) {
 		t.Fatal("client creation with unavailable mysql did not cause error")
 	}
 }
+
+func Test_Transaction(t *testing.T) {
+	// Separate database as other tests count all log entries
+	dbc, err := NewClient("sqlite3", "file:transaction?mode=memory&cache=shared")
+	if err != nil {
+		t.Fatalf("unable to create database client: %s", err)
+	}
+	t.Cleanup(func() { _ = dbc.Close() })
+
+	var (
+		ce      = CatalogEntry{Name: "txapp", Tag: "latest"}
+		errTest = errors.New("test error")
+	)
+
+	err = dbc.Transaction(func(tx *Client) error {
+		if err := tx.Logs.Add(&LogEntry{CatalogName: ce.Name, CatalogTag: ce.Tag, Timestamp: time.Now(), VersionTo: "1.0.0"}); err != nil {
+			return err
+		}
+		return errTest
+	})
+	if !errors.Is(err, errTest) {
+		t.Fatalf("expected transaction to return test error, got %v", err)
+	}
+
+	logs, err := dbc.Logs.ListForCatalogEntry(&ce, 10, 0)
+	if err != nil {
+		t.Fatalf("listing logs: %s", err)
+	}
+	if len(logs) != 0 {
+		t.Errorf("expected log entry to be rolled back, got %d entries", len(logs))
+	}
+
+	if err = dbc.Transaction(func(tx *Client) error {
+		if err := tx.Logs.Add(&LogEntry{CatalogName: ce.Name, CatalogTag: ce.Tag, Timestamp: time.Now(), VersionTo: "1.0.0"}); err != nil {
+			return err
+		}
+		return tx.Catalog.PutMeta(&CatalogMeta{CatalogName: ce.Name, CatalogTag: ce.Tag, CurrentVersion: "1.0.0"})
+	}); err != nil {
+		t.Fatalf("executing transaction: %s", err)
+	}
+
+	if logs, _ = dbc.Logs.ListForCatalogEntry(&ce, 10, 0); len(logs) != 1 {
+		t.Errorf("expected one log entry, got %d", len(logs))
+	}
+
+	if cm, _ := dbc.Catalog.GetMeta(&ce); cm.CurrentVersion != "1.0.0" {
+		t.Errorf("expected meta to be stored, got version %q", cm.CurrentVersion)
+	}
+}
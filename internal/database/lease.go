@@ -0,0 +1,94 @@
+package database
+
+import (
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type (
+	// Lease represents a named lock held by one instance until it
+	// expires or is released
+	Lease struct {
+		Name      string    `gorm:"primaryKey"`
+		Holder    string    `gorm:"not null"`
+		ExpiresAt time.Time `gorm:"not null"`
+	}
+
+	// gormLeaseStore implements the LeaseStore for SQL databases
+	gormLeaseStore struct {
+		db *gorm.DB
+	}
+)
+
+// Acquire tries to acquire or renew the lease with the given name for
+// the holder. It returns true if the holder owns the lease for the
+// given TTL afterwards. The expiry is calculated and compared using
+// the clock of the database as the clocks of the replicas might
+// differ.
+func (l gormLeaseStore) Acquire(name, holder string, ttl time.Duration) (bool, error) {
+	// Take over the lease if we already own it or it has expired, this
+	// is atomic as the database locks the row while updating
+	res := l.db.
+		Model(&Lease{}).
+		Where("name = ? AND (holder = ? OR expires_at < ?)", name, holder, l.dbTime(0)).
+		Updates(map[string]any{
+			"expires_at": l.dbTime(ttl),
+			"holder":     holder,
+		})
+	if res.Error != nil {
+		return false, fmt.Errorf("updating lease: %w", res.Error)
+	}
+
+	if res.RowsAffected > 0 {
+		return true, nil
+	}
+
+	// The lease is either held by someone else or does not exist yet,
+	// in the latter case only one of the instances can create it
+	res = l.db.
+		Model(&Lease{}).
+		Clauses(clause.OnConflict{DoNothing: true}).
+		Create(map[string]any{
+			"expires_at": l.dbTime(ttl),
+			"holder":     holder,
+			"name":       name,
+		})
+	if res.Error != nil {
+		return false, fmt.Errorf("creating lease: %w", res.Error)
+	}
+
+	return res.RowsAffected > 0, nil
+}
+
+// Release gives up the lease with the given name if it is owned by
+// the holder
+func (l gormLeaseStore) Release(name, holder string) error {
+	if err := l.db.
+		Where("name = ? AND holder = ?", name, holder).
+		Delete(&Lease{}).
+		Error; err != nil {
+		return fmt.Errorf("deleting lease: %w", err)
+	}
+
+	return nil
+}
+
+// dbTime returns an expression for the current time of the database
+// moved by the given offset
+func (l gormLeaseStore) dbTime(offset time.Duration) clause.Expr {
+	switch l.db.Name() {
+	case "mysql":
+		return gorm.Expr("TIMESTAMPADD(MICROSECOND, ?, UTC_TIMESTAMP(6))", offset.Microseconds())
+
+	case "postgres":
+		return gorm.Expr("CURRENT_TIMESTAMP + ? * INTERVAL '1 microsecond'", offset.Microseconds())
+
+	default:
+		// SQLite stores times as text, the format matches the one used
+		// by the driver for time.Time values in UTC
+		return gorm.Expr("strftime('%Y-%m-%d %H:%M:%f+00:00', 'now', ?)", fmt.Sprintf("%+.3f seconds", offset.Seconds()))
+	}
+}
@@ -0,0 +1,53 @@
+package database
+
+import (
+	"testing"
+	"time"
+)
+
+func Test_LeaseStorage(t *testing.T) {
+	dbc, err := NewClient("sqlite3", sqlliteMemoryDSN)
+	if err != nil {
+		t.Fatalf("unable to create database client: %s", err)
+	}
+
+	const lease = "test-lease"
+
+	for _, step := range []struct {
+		name   string
+		holder string
+		ttl    time.Duration
+		expect bool
+	}{
+		{"first holder acquires", "a", time.Minute, true},
+		{"second holder is rejected", "b", time.Minute, false},
+		{"first holder renews", "a", -time.Minute, true},
+		{"second holder takes expired lease", "b", time.Minute, true},
+		{"first holder is rejected", "a", time.Minute, false},
+	} {
+		acquired, err := dbc.Leases.Acquire(lease, step.holder, step.ttl)
+		if err != nil {
+			t.Fatalf("%s: acquiring lease: %s", step.name, err)
+		}
+
+		if acquired != step.expect {
+			t.Errorf("%s: expected acquired = %v, got %v", step.name, step.expect, acquired)
+		}
+	}
+
+	if err = dbc.Leases.Release(lease, "a"); err != nil {
+		t.Fatalf("releasing foreign lease: %s", err)
+	}
+
+	if acquired, _ := dbc.Leases.Acquire(lease, "a", time.Minute); acquired {
+		t.Error("releasing a foreign lease must not remove it")
+	}
+
+	if err = dbc.Leases.Release(lease, "b"); err != nil {
+		t.Fatalf("releasing lease: %s", err)
+	}
+
+	if acquired, _ := dbc.Leases.Acquire(lease, "a", time.Minute); !acquired {
+		t.Error("released lease must be available")
+	}
+}
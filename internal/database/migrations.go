@@ -0,0 +1,367 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+const (
+	mysqlSchemaLockName    = "latestver_schema_migration"
+	mysqlSchemaLockTimeout = 300 // seconds
+)
+
+type (
+	// schemaMigration is a single step of the database schema history,
+	// its version is the 1-based position in schemaMigrations. Once
+	// released a step must never be changed or removed: changes to the
+	// schema are done by appending a new step.
+	schemaMigration struct {
+		Name string
+
+		// Up applies the step, DialectUp may contain replacements of
+		// Up for specific dialects (as reported by the gorm Dialector)
+		Up        func(tx *gorm.DB) error
+		DialectUp map[string]func(tx *gorm.DB) error
+	}
+
+	// schemaVersion records an applied schemaMigration
+	schemaVersion struct {
+		Version   int `gorm:"primaryKey;autoIncrement:false"`
+		Name      string
+		AppliedAt time.Time
+	}
+
+	// Frozen models used in the migrations: they represent the schema
+	// at the time the step was written and must not be changed when the
+	// models used in the stores are changed
+	catalogMetaV1 struct {
+		CatalogName    string `gorm:"primaryKey"`
+		CatalogTag     string `gorm:"primaryKey"`
+		CurrentVersion string
+		Error          string
+		LastChecked    *time.Time
+		VersionTime    *time.Time
+	}
+
+	catalogMetaV2 struct {
+		CatalogName         string `gorm:"primaryKey"`
+		CatalogTag          string `gorm:"primaryKey"`
+		ConsecutiveFailures int
+		CurrentVersion      string
+		Error               string
+		FirstFailure        *time.Time
+		LastChecked         *time.Time
+		VersionTime         *time.Time
+	}
+
+	catalogMetaV5 struct {
+		CatalogName         string `gorm:"primaryKey"`
+		CatalogTag          string `gorm:"primaryKey"`
+		BlockedReason       string
+		BlockedVersion      string
+		ConsecutiveFailures int
+		CurrentVersion      string
+		Error               string
+		FirstFailure        *time.Time
+		LastChecked         *time.Time
+		VersionTime         *time.Time
+	}
+
+	checkAttemptV4 struct {
+		ID             uint64    `gorm:"primaryKey"`
+		CatalogName    string    `gorm:"index:check_catalog_key"`
+		CatalogTag     string    `gorm:"index:check_catalog_key"`
+		Started        time.Time `gorm:"index:,sort:desc"`
+		Finished       time.Time
+		DurationMS     int64
+		FetchedVersion string
+		Outcome        string
+		Error          string
+	}
+
+	checkAttemptV7 struct {
+		ID              uint64    `gorm:"primaryKey"`
+		CatalogName     string    `gorm:"index:check_catalog_key"`
+		CatalogTag      string    `gorm:"index:check_catalog_key"`
+		Started         time.Time `gorm:"index:,sort:desc"`
+		Finished        time.Time
+		DurationMS      int64
+		FetchedVersion  string
+		SelectedVersion string
+		Outcome         string
+		Error           string
+	}
+
+	leaseV3 struct {
+		Name      string    `gorm:"primaryKey"`
+		Holder    string    `gorm:"not null"`
+		ExpiresAt time.Time `gorm:"not null"`
+	}
+
+	logEntryV1 struct {
+		CatalogName string    `gorm:"index:catalog_key"`
+		CatalogTag  string    `gorm:"index:catalog_key"`
+		Timestamp   time.Time `gorm:"index:,sort:desc"`
+		VersionTo   string
+		VersionFrom string
+	}
+
+	logEntryV6 struct {
+		ID          uint64    `gorm:"primaryKey"`
+		CatalogName string    `gorm:"index:catalog_key"`
+		CatalogTag  string    `gorm:"index:catalog_key"`
+		Timestamp   time.Time `gorm:"index:,sort:desc"`
+		VersionTo   string
+		VersionFrom string
+	}
+)
+
+// schemaMigrations contains the whole history of the database schema.
+// All steps need to be idempotent as databases created before the
+// introduction of the schema_version table do not have them recorded.
+var schemaMigrations = []schemaMigration{
+	{
+		Name: "initial schema",
+		Up:   autoMigrate(&catalogMetaV1{}, &logEntryV1{}),
+	},
+	{
+		Name: "add failure tracking to catalog meta",
+		Up:   autoMigrate(&catalogMetaV2{}),
+	},
+	{
+		Name: "create leases",
+		Up:   autoMigrate(&leaseV3{}),
+	},
+	{
+		Name: "create check attempts",
+		Up:   autoMigrate(&checkAttemptV4{}),
+	},
+	{
+		Name: "add blocked version to catalog meta",
+		Up:   autoMigrate(&catalogMetaV5{}),
+	},
+	{
+		Name: "add primary key to log entries",
+		Up:   migrateLogEntryIDRebuild,
+		DialectUp: map[string]func(tx *gorm.DB) error{
+			"mysql": migrateLogEntryIDMySQL,
+		},
+	},
+	{
+		Name: "add selected version to check attempts",
+		Up:   autoMigrate(&checkAttemptV7{}),
+	},
+}
+
+// TableName sets the table name for the frozen model
+func (catalogMetaV1) TableName() string { return "catalog_meta" }
+
+// TableName sets the table name for the frozen model
+func (catalogMetaV2) TableName() string { return "catalog_meta" }
+
+// TableName sets the table name for the frozen model
+func (catalogMetaV5) TableName() string { return "catalog_meta" }
+
+// TableName sets the table name for the frozen model
+func (checkAttemptV4) TableName() string { return "check_attempts" }
+
+// TableName sets the table name for the frozen model
+func (checkAttemptV7) TableName() string { return "check_attempts" }
+
+// TableName sets the table name for the frozen model
+func (leaseV3) TableName() string { return "leases" }
+
+// TableName sets the table name for the frozen model
+func (logEntryV1) TableName() string { return "log_entries" }
+
+// TableName sets the table name for the frozen model
+func (logEntryV6) TableName() string { return "log_entries" }
+
+// TableName sets the table name for the schema history
+func (schemaVersion) TableName() string { return "schema_version" }
+
+// applySchemaMigrations applies the steps not yet recorded in the
+// schema_version table in order. With transactionalDDL each step is
+// recorded and applied within one transaction, otherwise the step is
+// recorded after it was applied successfully to allow retrying it. If
+// a step fails but was recorded in the meantime by another instance
+// starting concurrently, it is considered as applied.
+func applySchemaMigrations(db *gorm.DB, steps []schemaMigration, transactionalDDL bool) error {
+	var applied []schemaVersion
+	if err := db.Find(&applied).Error; err != nil {
+		return fmt.Errorf("listing applied migrations: %w", err)
+	}
+
+	isApplied := make(map[int]bool, len(applied))
+	for _, v := range applied {
+		isApplied[v.Version] = true
+
+		if v.Version > len(steps) {
+			log.WithField("version", v.Version).Warn("Database schema is newer than this version of the application")
+		}
+	}
+
+	dialect := db.Name()
+
+	for i, step := range steps {
+		version := i + 1
+		if isApplied[version] {
+			continue
+		}
+
+		up := step.Up
+		if fn, ok := step.DialectUp[dialect]; ok {
+			up = fn
+		}
+
+		logger := log.WithFields(log.Fields{"dialect": dialect, "name": step.Name, "version": version})
+		logger.Info("Applying database migration")
+
+		record := func(tx *gorm.DB) error {
+			if err := tx.Create(&schemaVersion{
+				Version:   version,
+				Name:      step.Name,
+				AppliedAt: time.Now().UTC(),
+			}).Error; err != nil {
+				return fmt.Errorf("recording migration: %w", err)
+			}
+			return nil
+		}
+
+		var err error
+		if transactionalDDL {
+			err = db.Transaction(func(tx *gorm.DB) error {
+				// Record the step first: the primary key makes concurrently
+				// starting instances wait for the same step
+				if err := record(tx); err != nil {
+					return err
+				}
+
+				return up(tx)
+			})
+		} else if err = up(db); err == nil {
+			err = record(db)
+		}
+
+		if err == nil {
+			continue
+		}
+
+		var count int64
+		if cerr := db.Model(&schemaVersion{}).Where("version = ?", version).Count(&count).Error; cerr == nil && count > 0 {
+			logger.Info("Database migration was applied by another instance")
+			continue
+		}
+
+		return fmt.Errorf("applying migration %d (%s): %w", version, step.Name, err)
+	}
+
+	return nil
+}
+
+func autoMigrate(models ...any) func(tx *gorm.DB) error {
+	return func(tx *gorm.DB) error {
+		if err := tx.AutoMigrate(models...); err != nil {
+			return fmt.Errorf("applying auto-migration: %w", err)
+		}
+
+		return nil
+	}
+}
+
+// migrateLogEntryIDMySQL adds the primary key in place: MySQL does not
+// support transactional DDL so the rebuild would not be atomic. The
+// IDs are assigned in insertion order which is the order of the log.
+func migrateLogEntryIDMySQL(tx *gorm.DB) error {
+	if tx.Migrator().HasColumn(&logEntryV6{}, "id") {
+		return nil
+	}
+
+	if err := tx.Exec(
+		"ALTER TABLE log_entries ADD COLUMN id BIGINT UNSIGNED NOT NULL AUTO_INCREMENT PRIMARY KEY FIRST",
+	).Error; err != nil {
+		return fmt.Errorf("adding id column: %w", err)
+	}
+
+	return nil
+}
+
+// migrateLogEntryIDRebuild rebuilds the log table with a primary key
+// as not all databases are able to add a primary key to an existing
+// table. The IDs are assigned in the order of the timestamps.
+func migrateLogEntryIDRebuild(tx *gorm.DB) error {
+	const legacyTable = "log_entries_legacy"
+
+	m := tx.Migrator()
+	if m.HasColumn(&logEntryV6{}, "id") {
+		return nil
+	}
+
+	// Indexes keep their names when renaming the table and would clash
+	// with the indexes of the new table
+	for _, idx := range []string{"catalog_key", "idx_log_entries_timestamp"} {
+		if m.HasIndex(&logEntryV1{}, idx) {
+			if err := m.DropIndex(&logEntryV1{}, idx); err != nil {
+				return fmt.Errorf("dropping index %q: %w", idx, err)
+			}
+		}
+	}
+
+	if err := m.RenameTable(&logEntryV1{}, legacyTable); err != nil {
+		return fmt.Errorf("renaming table: %w", err)
+	}
+
+	if err := m.AutoMigrate(&logEntryV6{}); err != nil {
+		return fmt.Errorf("creating table: %w", err)
+	}
+
+	if err := tx.Exec(
+		"INSERT INTO log_entries (catalog_name, catalog_tag, timestamp, version_to, version_from) " +
+			"SELECT catalog_name, catalog_tag, timestamp, version_to, version_from FROM " + legacyTable + " ORDER BY timestamp",
+	).Error; err != nil {
+		return fmt.Errorf("copying log entries: %w", err)
+	}
+
+	if err := m.DropTable(legacyTable); err != nil {
+		return fmt.Errorf("dropping legacy table: %w", err)
+	}
+
+	return nil
+}
+
+// migrateSchema applies all schemaMigrations not yet recorded in the
+// schema_version table in order
+func migrateSchema(db *gorm.DB) error {
+	if err := db.AutoMigrate(&schemaVersion{}); err != nil {
+		return fmt.Errorf("creating schema_version table: %w", err)
+	}
+
+	if db.Name() != "mysql" {
+		return applySchemaMigrations(db, schemaMigrations, true)
+	}
+
+	// MySQL implicitly commits DDL statements so the transaction cannot
+	// serialize concurrently starting instances: a named lock held by
+	// a single connection is used instead
+	return db.Connection(func(conn *gorm.DB) error { //nolint:wrapcheck // errors are wrapped inside
+		var locked int
+		if err := conn.Raw("SELECT GET_LOCK(?, ?)", mysqlSchemaLockName, mysqlSchemaLockTimeout).Scan(&locked).Error; err != nil {
+			return fmt.Errorf("acquiring schema lock: %w", err)
+		}
+
+		if locked != 1 {
+			return errors.New("timeout acquiring schema lock")
+		}
+		defer func() {
+			if err := conn.Exec("SELECT RELEASE_LOCK(?)", mysqlSchemaLockName).Error; err != nil {
+				log.WithError(err).Error("releasing schema lock")
+			}
+		}()
+
+		return applySchemaMigrations(conn, schemaMigrations, false)
+	})
+}
@@ -0,0 +1,142 @@
+package database
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+)
+
+func Test_MigrateLegacySchema(t *testing.T) {
+	const dsn = "file:legacyschema?mode=memory&cache=shared"
+
+	// Keep the legacy connection open to keep the in-memory database
+	legacy, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("opening legacy database: %s", err)
+	}
+
+	// Schema as created by AutoMigrate before versioned migrations
+	if err = legacy.AutoMigrate(&catalogMetaV1{}, &logEntryV1{}); err != nil {
+		t.Fatalf("creating legacy tables: %s", err)
+	}
+
+	rt := time.Now().UTC()
+	if err = legacy.Create([]logEntryV1{
+		{CatalogName: "app", CatalogTag: "latest", Timestamp: rt.Add(-1 * time.Hour), VersionFrom: "1.1.0", VersionTo: "1.2.0"},
+		{CatalogName: "app", CatalogTag: "latest", Timestamp: rt.Add(-2 * time.Hour), VersionFrom: "1.0.0", VersionTo: "1.1.0"},
+	}).Error; err != nil {
+		t.Fatalf("creating legacy entries: %s", err)
+	}
+
+	dbc, err := NewClient("sqlite3", dsn)
+	if err != nil {
+		t.Fatalf("unable to create database client: %s", err)
+	}
+	t.Cleanup(func() { _ = dbc.Close() })
+
+	var applied int64
+	if err = legacy.Model(&schemaVersion{}).Count(&applied).Error; err != nil {
+		t.Fatalf("counting applied migrations: %s", err)
+	}
+
+	if applied != int64(len(schemaMigrations)) {
+		t.Errorf("expected %d applied migrations, got %d", len(schemaMigrations), applied)
+	}
+
+	logs, err := dbc.Logs.List(100, 0)
+	if err != nil {
+		t.Fatalf("unable to fetch log entries: %s", err)
+	}
+
+	if len(logs) != 2 {
+		t.Fatalf("expected 2 migrated log entries, got %d", len(logs))
+	}
+
+	if logs[0].VersionTo != "1.2.0" || logs[0].ID != 2 || logs[1].ID != 1 {
+		t.Errorf("unexpected migrated log entries: %+v", logs)
+	}
+
+	// Running the migrations again must not change anything
+	if err = migrateSchema(legacy); err != nil {
+		t.Fatalf("re-running migrations: %s", err)
+	}
+}
+
+func Test_MigrationsMatchModels(t *testing.T) {
+	dbc, err := NewClient("sqlite3", "file:migrationsmodels?mode=memory&cache=shared")
+	if err != nil {
+		t.Fatalf("unable to create database client: %s", err)
+	}
+	t.Cleanup(func() { _ = dbc.Close() })
+
+	db := dbc.backend.(gormBackend).db
+
+	// Every field of the models must have been created by a migration
+	for _, model := range []any{&CatalogMeta{}, &CheckAttempt{}, &Lease{}, &LogEntry{}} {
+		stmt := &gorm.Statement{DB: db}
+		if err = stmt.Parse(model); err != nil {
+			t.Fatalf("parsing model %T: %s", model, err)
+		}
+
+		for _, field := range stmt.Schema.Fields {
+			if field.DBName != "" && !db.Migrator().HasColumn(model, field.DBName) {
+				t.Errorf("column %s.%s is not created by any migration", stmt.Schema.Table, field.DBName)
+			}
+		}
+	}
+}
+
+func Test_ApplySchemaMigrationsWithoutTransactionalDDL(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file:nontxddl?mode=memory&cache=shared"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("opening database: %s", err)
+	}
+
+	if err = db.AutoMigrate(&schemaVersion{}); err != nil {
+		t.Fatalf("creating schema_version table: %s", err)
+	}
+
+	var (
+		failing = errors.New("step failed")
+		noop    = func(*gorm.DB) error { return nil }
+		calls   int
+	)
+
+	steps := []schemaMigration{
+		{Name: "working", Up: noop},
+		{Name: "failing", Up: func(*gorm.DB) error { calls++; return failing }},
+		{Name: "applied concurrently", Up: func(tx *gorm.DB) error {
+			// Another instance finishes the same step while we apply it
+			return tx.Create(&schemaVersion{Version: 3, Name: "applied concurrently"}).Error
+		}},
+	}
+
+	isRecorded := func(version int) bool {
+		var count int64
+		if err := db.Model(&schemaVersion{}).Where("version = ?", version).Count(&count).Error; err != nil {
+			t.Fatalf("counting migrations: %s", err)
+		}
+		return count > 0
+	}
+
+	if err = applySchemaMigrations(db, steps, false); !errors.Is(err, failing) {
+		t.Fatalf("expected failing step to return its error, got %v", err)
+	}
+
+	if !isRecorded(1) || isRecorded(2) {
+		t.Fatal("failed step must not be recorded")
+	}
+
+	// The failed step is retried on the next start
+	steps[1].Up = func(*gorm.DB) error { calls++; return nil }
+	if err = applySchemaMigrations(db, steps, false); err != nil {
+		t.Fatalf("retrying migrations: %s", err)
+	}
+
+	if calls != 2 || !isRecorded(2) || !isRecorded(3) {
+		t.Errorf("expected failed step to be retried and all steps recorded, got %d calls", calls)
+	}
+}
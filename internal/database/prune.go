@@ -0,0 +1,187 @@
+package database
+
+import (
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type (
+	// PruneOptions configures which data to remove in Client.Prune,
+	// zero values disable the corresponding rule
+	PruneOptions struct {
+		// Catalog contains the entries to keep when RemoveOrphans is set
+		Catalog       []CatalogEntry
+		RemoveOrphans bool
+
+		CheckMaxAge   time.Duration
+		LogMaxAge     time.Duration
+		LogMaxEntries int
+	}
+
+	// PruneResult contains the number of removed rows per table
+	PruneResult struct {
+		Checks int64
+		Logs   int64
+		Metas  int64
+	}
+
+	catalogKey struct {
+		CatalogName string
+		CatalogTag  string
+	}
+)
+
+// Prune removes check attempts and log entries exceeding the limits
+// given in the options and data of catalog entries no longer present
+func (c Client) Prune(opts PruneOptions) (PruneResult, error) {
+	res, err := c.backend.prune(opts)
+	if err != nil {
+		return res, fmt.Errorf("pruning database: %w", err)
+	}
+
+	return res, nil
+}
+
+func (g gormBackend) prune(opts PruneOptions) (res PruneResult, err error) {
+	err = g.db.Transaction(func(tx *gorm.DB) error {
+		if opts.RemoveOrphans {
+			orphans, err := pruneOrphans(tx, opts.Catalog)
+			if err != nil {
+				return fmt.Errorf("removing orphans: %w", err)
+			}
+			res = orphans
+		}
+
+		if opts.CheckMaxAge > 0 {
+			n, err := deleteOlderThan(tx, &CheckAttempt{}, "started", time.Now().Add(-opts.CheckMaxAge))
+			if err != nil {
+				return fmt.Errorf("removing old check attempts: %w", err)
+			}
+			res.Checks += n
+		}
+
+		if opts.LogMaxAge > 0 {
+			n, err := deleteOlderThan(tx, &LogEntry{}, "timestamp", time.Now().Add(-opts.LogMaxAge))
+			if err != nil {
+				return fmt.Errorf("removing old log entries: %w", err)
+			}
+			res.Logs += n
+		}
+
+		if opts.LogMaxEntries > 0 {
+			n, err := deleteExceedingPerEntry(tx, opts.LogMaxEntries)
+			if err != nil {
+				return fmt.Errorf("removing exceeding log entries: %w", err)
+			}
+			res.Logs += n
+		}
+
+		return nil
+	})
+
+	return res, err //nolint:wrapcheck // wrapped by Client.Prune
+}
+
+// deleteExceedingPerEntry removes all but the newest max log entries
+// for every catalog entry
+func deleteExceedingPerEntry(db *gorm.DB, maxEntries int) (int64, error) {
+	keys, err := distinctCatalogKeys(db, &LogEntry{})
+	if err != nil {
+		return 0, err
+	}
+
+	var deleted int64
+	for _, k := range keys {
+		filter := &LogEntry{CatalogName: k.CatalogName, CatalogTag: k.CatalogTag}
+
+		// Find the newest entry which should not be kept anymore
+		var cutoff []LogEntry
+		if err = db.
+			Where(filter).
+			Order("timestamp desc, id desc").
+			Offset(maxEntries).Limit(1).
+			Find(&cutoff).
+			Error; err != nil {
+			return deleted, fmt.Errorf("finding cutoff for %s:%s: %w", k.CatalogName, k.CatalogTag, err)
+		}
+
+		if len(cutoff) == 0 {
+			// Less entries than allowed
+			continue
+		}
+
+		del := db.
+			Where(filter).
+			Where("timestamp < ? OR (timestamp = ? AND id <= ?)", cutoff[0].Timestamp, cutoff[0].Timestamp, cutoff[0].ID).
+			Delete(&LogEntry{})
+		if del.Error != nil {
+			return deleted, fmt.Errorf("deleting log entries for %s:%s: %w", k.CatalogName, k.CatalogTag, del.Error)
+		}
+		deleted += del.RowsAffected
+	}
+
+	return deleted, nil
+}
+
+func deleteOlderThan(db *gorm.DB, model any, column string, before time.Time) (int64, error) {
+	res := db.
+		Where(clause.Lt{Column: column, Value: before.UTC()}).
+		Delete(model)
+	if res.Error != nil {
+		return 0, fmt.Errorf("deleting rows: %w", res.Error)
+	}
+
+	return res.RowsAffected, nil
+}
+
+func distinctCatalogKeys(db *gorm.DB, model any) (keys []catalogKey, err error) {
+	if err = db.
+		Model(model).
+		Distinct("catalog_name", "catalog_tag").
+		Scan(&keys).
+		Error; err != nil {
+		return nil, fmt.Errorf("listing catalog keys: %w", err)
+	}
+
+	return keys, nil
+}
+
+func pruneOrphans(db *gorm.DB, catalog []CatalogEntry) (res PruneResult, err error) {
+	known := make(map[catalogKey]bool, len(catalog))
+	for _, ce := range catalog {
+		known[catalogKey{ce.Name, ce.Tag}] = true
+	}
+
+	for _, tbl := range []struct {
+		model any
+		count *int64
+	}{
+		{&CatalogMeta{}, &res.Metas},
+		{&CheckAttempt{}, &res.Checks},
+		{&LogEntry{}, &res.Logs},
+	} {
+		keys, err := distinctCatalogKeys(db, tbl.model)
+		if err != nil {
+			return res, err
+		}
+
+		for _, k := range keys {
+			if known[k] {
+				continue
+			}
+
+			del := db.
+				Where("catalog_name = ? AND catalog_tag = ?", k.CatalogName, k.CatalogTag).
+				Delete(tbl.model)
+			if del.Error != nil {
+				return res, fmt.Errorf("deleting rows for %s:%s: %w", k.CatalogName, k.CatalogTag, del.Error)
+			}
+			*tbl.count += del.RowsAffected
+		}
+	}
+
+	return res, nil
+}
@@ -0,0 +1,73 @@
+package database
+
+import (
+	"testing"
+	"time"
+)
+
+func Test_Prune(t *testing.T) {
+	// Separate database as other tests count all log entries
+	dbc, err := NewClient("sqlite3", "file:prune?mode=memory&cache=shared")
+	if err != nil {
+		t.Fatalf("unable to create database client: %s", err)
+	}
+	t.Cleanup(func() { _ = dbc.Close() })
+
+	var (
+		kept    = CatalogEntry{Name: "kept", Tag: "latest"}
+		removed = CatalogEntry{Name: "removed", Tag: "latest"}
+		now     = time.Now().UTC().Truncate(time.Second)
+	)
+
+	for _, ce := range []CatalogEntry{kept, removed} {
+		if err = dbc.Catalog.PutMeta(&CatalogMeta{CatalogName: ce.Name, CatalogTag: ce.Tag}); err != nil {
+			t.Fatalf("storing meta: %s", err)
+		}
+
+		for i := range 5 {
+			if err = dbc.Logs.Add(&LogEntry{CatalogName: ce.Name, CatalogTag: ce.Tag, Timestamp: now.Add(-time.Duration(i) * 24 * time.Hour)}); err != nil {
+				t.Fatalf("adding log entry: %s", err)
+			}
+
+			if err = dbc.Checks.Add(&CheckAttempt{CatalogName: ce.Name, CatalogTag: ce.Tag, Started: now.Add(-time.Duration(i) * 24 * time.Hour)}); err != nil {
+				t.Fatalf("adding check attempt: %s", err)
+			}
+		}
+	}
+
+	res, err := dbc.Prune(PruneOptions{
+		Catalog:       []CatalogEntry{kept},
+		RemoveOrphans: true,
+		CheckMaxAge:   36 * time.Hour,
+		LogMaxAge:     84 * time.Hour,
+		LogMaxEntries: 3,
+	})
+	if err != nil {
+		t.Fatalf("pruning database: %s", err)
+	}
+
+	// Removed entry: 1 meta, 5 checks, 5 logs
+	// Kept entry: 3 checks (older than 36h), 1 log by age (older than 84h), 1 log by count
+	if res != (PruneResult{Checks: 8, Logs: 7, Metas: 1}) {
+		t.Errorf("unexpected prune result: %+v", res)
+	}
+
+	logs, err := dbc.Logs.List(100, 0)
+	if err != nil {
+		t.Fatalf("listing logs: %s", err)
+	}
+
+	if len(logs) != 3 {
+		t.Fatalf("expected 3 remaining log entries, got %d", len(logs))
+	}
+
+	for _, le := range logs {
+		if le.CatalogName != kept.Name {
+			t.Errorf("log entry of removed catalog entry was kept")
+		}
+	}
+
+	if checks, _ := dbc.Checks.ListForCatalogEntry(&kept, 100, 0); len(checks) != 2 {
+		t.Errorf("expected 2 remaining check attempts, got %d", len(checks))
+	}
+}
@@ -0,0 +1,81 @@
+package database
+
+import (
+	"time"
+)
+
+type (
+	// CatalogMetaStore is an accessor for the CatalogMeta of the
+	// catalog entries
+	CatalogMetaStore interface {
+		// GetMeta fetches the stored CatalogMeta for the CatalogEntry,
+		// an empty CatalogMeta is returned if none is stored
+		GetMeta(ce *CatalogEntry) (*CatalogMeta, error)
+		// ListMeta fetches all stored CatalogMeta ordered by catalog
+		// name and tag
+		ListMeta() ([]CatalogMeta, error)
+		// PutMeta stores the updated CatalogMeta
+		PutMeta(cm *CatalogMeta) error
+	}
+
+	// CheckStore is an accessor for the check attempt history
+	CheckStore interface {
+		// Add creates a new CheckAttempt inside the CheckStore
+		Add(ca *CheckAttempt) error
+		// ListForCatalogEntry retrieves the check attempts of the catalog
+		// entry by page, latest attempts first
+		ListForCatalogEntry(ce *CatalogEntry, num, page int) ([]CheckAttempt, error)
+	}
+
+	// LeaseStore is an accessor for named leases held by one instance
+	LeaseStore interface {
+		// Acquire tries to acquire or renew the lease with the given name
+		// for the holder. It returns true if the holder owns the lease for
+		// the given TTL afterwards.
+		Acquire(name, holder string, ttl time.Duration) (bool, error)
+		// Release gives up the lease with the given name if it is owned
+		// by the holder
+		Release(name, holder string) error
+	}
+
+	// LogStore is an accessor for the update log
+	LogStore interface {
+		// Add creates a new LogEntry inside the LogStore
+		Add(le *LogEntry) error
+		// List retrieves unfiltered log entries by page
+		List(num, page int) ([]LogEntry, error)
+		// ListForCatalogEntry retrieves filtered log entries by page
+		ListForCatalogEntry(ce *CatalogEntry, num, page int) ([]LogEntry, error)
+		// Query retrieves the log entries matching the query, newest first
+		Query(q LogQuery) ([]LogEntry, error)
+	}
+
+	// backend contains the storage specific operations of the Client
+	// which are not part of the stores
+	backend interface {
+		close() error
+		transaction(fn func(tx *Client) error) error
+		prune(opts PruneOptions) (PruneResult, error)
+
+		// Bulk access used to copy, verify and back up the data, the
+		// callbacks are called with batches ordered by primary key
+		eachCatalogMeta(batchSize int, fn func([]CatalogMeta) error) error
+		eachCheckAttempt(batchSize int, fn func([]CheckAttempt) error) error
+		eachLogEntry(batchSize int, fn func([]LogEntry) error) error
+
+		// Writes used to copy the data returning the number of rows
+		// written: catalog meta is upserted by its catalog entry, check
+		// attempts and log entries already stored (compared ignoring
+		// their IDs) are skipped, the others get new IDs
+		insertCheckAttempts(checks []CheckAttempt) (int64, error)
+		insertLogEntries(logs []LogEntry) (int64, error)
+		upsertCatalogMetas(metas []CatalogMeta) (int64, error)
+
+		// hasCheckAttempt reports whether a check attempt for the same
+		// catalog entry started at the same time is already stored
+		hasCheckAttempt(ca CheckAttempt) (bool, error)
+		// hasLogEntry reports whether a log entry for the same change
+		// (ignoring its ID) is already stored
+		hasLogEntry(le LogEntry) (bool, error)
+	}
+)
@@ -3,6 +3,7 @@ package database
 import (
 	"errors"
 	"fmt"
+	"slices"
 	"strings"
 	"time"
 
@@ -22,9 +23,18 @@ type (
 		Fetcher       string                           `json:"-" yaml:"fetcher"`
 		FetcherConfig *fieldcollection.FieldCollection `json:"-" yaml:"fetcher_config"`
 
+		CheckInterval time.Duration `json:"-" yaml:"check_interval"`
+		CheckSchedule string        `json:"-" yaml:"check_schedule"`
+
+		Webhook *CatalogWebhook `json:"-" yaml:"webhook"`
+
+		Transform         version.Transform   `json:"-" yaml:"transform"`
 		VersionConstraint *version.Constraint `json:"-" yaml:"version_constraint"`
 
 		Links []CatalogLink `json:"links" yaml:"links"`
+
+		Group  string            `json:"group,omitempty" yaml:"group"`
+		Labels map[string]string `json:"labels,omitempty" yaml:"labels"`
 	}
 
 	// CatalogLink represents a link assigned to a CatalogEntry
@@ -34,18 +44,30 @@ type (
 		URL       string `json:"url" yaml:"url"`
 	}
 
+	// CatalogWebhook configures which webhooks trigger a check of the
+	// CatalogEntry
+	CatalogWebhook struct {
+		Repository string `yaml:"repository"`
+		Secret     string `yaml:"secret"`
+	}
+
 	// CatalogMeta contains meta-information about the catalog entry
 	CatalogMeta struct {
-		CatalogName    string     `gorm:"primaryKey" json:"-"`
-		CatalogTag     string     `gorm:"primaryKey" json:"-"`
-		CurrentVersion string     `json:"current_version,omitempty"`
-		Error          string     `json:"error,omitempty"`
-		LastChecked    *time.Time `json:"last_checked,omitempty"`
-		VersionTime    *time.Time `json:"version_time,omitempty"`
+		CatalogName         string     `gorm:"primaryKey" json:"-"`
+		CatalogTag          string     `gorm:"primaryKey" json:"-"`
+		BlockedReason       string     `json:"blocked_reason,omitempty"`
+		BlockedVersion      string     `json:"blocked_version,omitempty"`
+		ConsecutiveFailures int        `json:"consecutive_failures,omitempty"`
+		CurrentVersion      string     `json:"current_version,omitempty"`
+		Error               string     `json:"error,omitempty"`
+		FirstFailure        *time.Time `json:"first_failure,omitempty"`
+		LastChecked         *time.Time `json:"last_checked,omitempty"`
+		VersionTime         *time.Time `json:"version_time,omitempty"`
 	}
 
 	// LogEntry represents a single version change for a given catalog entry
 	LogEntry struct {
+		ID          uint64    `gorm:"primaryKey" json:"id"`
 		CatalogName string    `gorm:"index:catalog_key" json:"catalog_name"`
 		CatalogTag  string    `gorm:"index:catalog_key" json:"catalog_tag"`
 		Timestamp   time.Time `gorm:"index:,sort:desc" json:"timestamp"`
@@ -53,28 +75,62 @@ type (
 		VersionFrom string    `json:"version_from"`
 	}
 
-	// CatalogMetaStore is an accessor for the meta store and wraps a Client
-	CatalogMetaStore struct {
-		c *Client
+	// LogQuery filters and paginates the log entries returned by
+	// LogStore.Query: either a page or one of the After / Before
+	// cursors (IDs of log entries) should be used
+	LogQuery struct {
+		CatalogEntry *CatalogEntry
+		// CatalogEntries restricts the log to the given entries when not
+		// nil: an empty list does not match any entry
+		CatalogEntries []CatalogEntry
+
+		Num  int
+		Page int
+
+		After  uint64
+		Before uint64
+
+		Since time.Time
+		Until time.Time
+	}
+
+	// gormCatalogMetaStore implements the CatalogMetaStore for SQL
+	// databases
+	gormCatalogMetaStore struct {
+		db *gorm.DB
 	}
 
-	// LogStore is an accessor for the log store and wraps a Client
-	LogStore struct {
-		c *Client
+	// gormLogStore implements the LogStore for SQL databases
+	gormLogStore struct {
+		db *gorm.DB
 	}
 )
 
+// ErrUnknownCursor signals the cursor given in a LogQuery does not
+// reference an existing log entry
+var ErrUnknownCursor = errors.New("unknown cursor")
+
 // Key returns the name / tag combination as a single key
 func (c CatalogEntry) Key() string { return strings.Join([]string{c.Name, c.Tag}, ":") }
 
+// VersionTransform returns the Transform to apply to fetched versions,
+// falling back to the version.DefaultTransform if none is configured
+func (c CatalogEntry) VersionTransform() version.Transform {
+	if c.Transform == nil {
+		return version.DefaultTransform
+	}
+
+	return c.Transform
+}
+
 // GetMeta fetches the current database stored CatalogMeta for the CatalogEntry
-func (c CatalogMetaStore) GetMeta(ce *CatalogEntry) (*CatalogMeta, error) {
+func (c gormCatalogMetaStore) GetMeta(ce *CatalogEntry) (*CatalogMeta, error) {
 	out := &CatalogMeta{
 		CatalogName: ce.Name,
 		CatalogTag:  ce.Tag,
 	}
 
-	err := c.c.db.
+	err := c.db.
 		Where(out).
 		First(out).Error
 
@@ -90,42 +146,27 @@ func (c CatalogMetaStore) GetMeta(ce *CatalogEntry) (*CatalogMeta, error) {
 	return out, nil
 }
 
-// Migrate applies the updated database schema for the CatalogMetaStore
-func (c CatalogMetaStore) Migrate(dest *Client) error {
-	var metas []*CatalogMeta
-	if err := c.c.db.Find(&metas).Error; err != nil {
-		return fmt.Errorf("listing meta entries: %w", err)
-	}
-
-	for _, m := range metas {
-		if err := dest.Catalog.PutMeta(m); err != nil {
-			return fmt.Errorf("storing meta to dest database: %w", err)
-		}
+// ListMeta fetches all stored CatalogMeta ordered by catalog name and tag
+func (c gormCatalogMetaStore) ListMeta() (out []CatalogMeta, err error) {
+	if err = c.db.Order("catalog_name, catalog_tag").Find(&out).Error; err != nil {
+		return nil, fmt.Errorf("querying metadata: %w", err)
 	}
 
-	return nil
+	return out, nil
 }
 
 // PutMeta stores the updated CatalogMeta
-func (c CatalogMetaStore) PutMeta(cm *CatalogMeta) error {
-	if err := c.c.db.Clauses(clause.OnConflict{UpdateAll: true}).Create(cm).Error; err != nil {
+func (c gormCatalogMetaStore) PutMeta(cm *CatalogMeta) error {
+	if err := c.db.Clauses(clause.OnConflict{UpdateAll: true}).Create(cm).Error; err != nil {
 		return fmt.Errorf("writing catalog meta: %w", err)
 	}
 
 	return nil
 }
 
-func (c CatalogMetaStore) ensureTable() error {
-	if err := c.c.db.AutoMigrate(&CatalogMeta{}); err != nil {
-		return fmt.Errorf("applying migration: %w", err)
-	}
-
-	return nil
-}
-
 // Add creates a new LogEntry inside the LogStore
-func (l LogStore) Add(le *LogEntry) error {
-	if err := l.c.db.Create(le).Error; err != nil {
+func (l gormLogStore) Add(le *LogEntry) error {
+	if err := l.db.Create(le).Error; err != nil {
 		return fmt.Errorf("writing log entry: %w", err)
 	}
 
@@ -133,47 +174,94 @@ func (l LogStore) Add(le *LogEntry) error {
 }
 
 // List retrieves unfiltered log entries by page
-func (l LogStore) List(num, page int) ([]LogEntry, error) {
-	return l.listWithFilter(l.c.db, num, page)
+func (l gormLogStore) List(num, page int) ([]LogEntry, error) {
+	return l.Query(LogQuery{Num: num, Page: page})
 }
 
 // ListForCatalogEntry retrieves filered log entries by page
-func (l LogStore) ListForCatalogEntry(ce *CatalogEntry, num, page int) ([]LogEntry, error) {
-	return l.listWithFilter(l.c.db.Where(&LogEntry{CatalogName: ce.Name, CatalogTag: ce.Tag}), num, page)
+func (l gormLogStore) ListForCatalogEntry(ce *CatalogEntry, num, page int) ([]LogEntry, error) {
+	return l.Query(LogQuery{CatalogEntry: ce, Num: num, Page: page})
 }
 
-// Migrate applies the updated database schema for the LogStore
-func (l LogStore) Migrate(dest *Client) error {
-	var logs []*LogEntry
-	if err := l.c.db.Find(&logs).Error; err != nil {
-		return fmt.Errorf("listing log entries: %w", err)
+// Query retrieves the log entries matching the query, newest first
+func (l gormLogStore) Query(q LogQuery) (out []LogEntry, err error) {
+	filter := l.db.Model(&LogEntry{})
+
+	if q.CatalogEntry != nil {
+		filter = filter.Where(&LogEntry{CatalogName: q.CatalogEntry.Name, CatalogTag: q.CatalogEntry.Tag})
 	}
 
-	for _, l := range logs {
-		if err := dest.Logs.Add(l); err != nil {
-			return fmt.Errorf("storing log to dest database: %w", err)
+	if q.CatalogEntries != nil {
+		if len(q.CatalogEntries) == 0 {
+			return []LogEntry{}, nil
 		}
+
+		entries := l.db.Where("1 = 0")
+		for _, ce := range q.CatalogEntries {
+			entries = entries.Or(map[string]any{"catalog_name": ce.Name, "catalog_tag": ce.Tag})
+		}
+		filter = filter.Where(entries)
 	}
 
-	return nil
-}
+	if !q.Since.IsZero() {
+		filter = filter.Where("timestamp >= ?", q.Since.UTC())
+	}
 
-func (l LogStore) ensureTable() error {
-	if err := l.c.db.AutoMigrate(&LogEntry{}); err != nil {
-		return fmt.Errorf("applying migration: %w", err)
+	if !q.Until.IsZero() {
+		filter = filter.Where("timestamp < ?", q.Until.UTC())
 	}
 
-	return nil
-}
+	switch {
+	case q.After > 0:
+		cursor, err := l.get(q.After)
+		if err != nil {
+			return nil, err
+		}
+
+		// Fetch the entries directly following the cursor and reverse
+		// them afterwards to keep the newest-first order
+		filter = filter.
+			Where("timestamp > ? OR (timestamp = ? AND id > ?)", cursor.Timestamp, cursor.Timestamp, cursor.ID).
+			Order("timestamp asc, id asc").
+			Limit(q.Num)
+
+	case q.Before > 0:
+		cursor, err := l.get(q.Before)
+		if err != nil {
+			return nil, err
+		}
+
+		filter = filter.
+			Where("timestamp < ? OR (timestamp = ? AND id < ?)", cursor.Timestamp, cursor.Timestamp, cursor.ID).
+			Order("timestamp desc, id desc").
+			Limit(q.Num)
+
+	default:
+		filter = filter.Order("timestamp desc, id desc").Limit(q.Num).Offset(q.Num * q.Page)
+	}
 
-func (LogStore) listWithFilter(filter *gorm.DB, num, page int) (out []LogEntry, err error) {
-	if err = filter.
-		Order("timestamp desc").
-		Limit(num).Offset(num * page).
-		Find(&out).
-		Error; err != nil {
+	if err = filter.Find(&out).Error; err != nil {
 		return nil, fmt.Errorf("fetching log entries: %w", err)
 	}
 
+	if q.After > 0 {
+		slices.Reverse(out)
+	}
+
 	return out, nil
 }
+
+// get retrieves the log entry with the given ID to be used as a
+// cursor, ErrUnknownCursor is returned when it does not exist
+func (l gormLogStore) get(id uint64) (*LogEntry, error) {
+	var entries []LogEntry
+	if err := l.db.Where("id = ?", id).Limit(1).Find(&entries).Error; err != nil {
+		return nil, fmt.Errorf("fetching cursor entry: %w", err)
+	}
+
+	if len(entries) == 0 {
+		return nil, ErrUnknownCursor
+	}
+
+	return &entries[0], nil
+}
@@ -1,6 +1,8 @@
 package database
 
 import (
+	"errors"
+	"slices"
 	"testing"
 	"time"
 )
@@ -83,6 +85,89 @@ func Test_CatalogMetaStorage(t *testing.T) {
 	}
 }
 
+func Test_CatalogMetaList(t *testing.T) {
+	dbc, err := NewClient("sqlite3", "file:metalist?mode=memory&cache=shared")
+	if err != nil {
+		t.Fatalf("unable to create database client: %s", err)
+	}
+	t.Cleanup(func() { _ = dbc.Close() })
+
+	for _, ce := range []CatalogEntry{{Name: "b", Tag: "latest"}, {Name: "a", Tag: "stable"}, {Name: "a", Tag: "latest"}} {
+		if err = dbc.Catalog.PutMeta(&CatalogMeta{CatalogName: ce.Name, CatalogTag: ce.Tag, CurrentVersion: ce.Tag}); err != nil {
+			t.Fatalf("unable to store catalog meta: %s", err)
+		}
+	}
+
+	metas, err := dbc.Catalog.ListMeta()
+	if err != nil {
+		t.Fatalf("unable to list catalog meta: %s", err)
+	}
+
+	var keys []string
+	for _, cm := range metas {
+		keys = append(keys, cm.CatalogName+":"+cm.CatalogTag)
+	}
+
+	if expect := []string{"a:latest", "a:stable", "b:latest"}; !slices.Equal(keys, expect) {
+		t.Errorf("expected meta %v, got %v", expect, keys)
+	}
+}
+
+func Test_LogQueryCatalogEntries(t *testing.T) {
+	gormClient, err := NewClient("sqlite3", "file:logquerycatalogentries?mode=memory&cache=shared")
+	if err != nil {
+		t.Fatalf("unable to create database client: %s", err)
+	}
+	t.Cleanup(func() { _ = gormClient.Close() })
+
+	var (
+		appLatest   = CatalogEntry{Name: "app", Tag: "latest"}
+		appStable   = CatalogEntry{Name: "app", Tag: "stable"}
+		otherLatest = CatalogEntry{Name: "other", Tag: "latest"}
+		otherStable = CatalogEntry{Name: "other", Tag: "stable"}
+		rt          = time.Now().UTC().Truncate(time.Hour)
+	)
+
+	for name, dbc := range map[string]*Client{"gorm": gormClient, "bolt": newTestBoltClient(t)} {
+		// Log IDs 1-4 in the order of the entries
+		for i, ce := range []CatalogEntry{appLatest, appStable, otherLatest, otherStable} {
+			if err = dbc.Logs.Add(&LogEntry{CatalogName: ce.Name, CatalogTag: ce.Tag, Timestamp: rt.Add(time.Duration(i) * time.Minute)}); err != nil {
+				t.Fatalf("%s: adding log entry: %s", name, err)
+			}
+		}
+
+		for _, tc := range []struct {
+			name   string
+			query  LogQuery
+			expect []uint64
+		}{
+			{"no restriction", LogQuery{Num: 10}, []uint64{4, 3, 2, 1}},
+			{"single entry", LogQuery{CatalogEntries: []CatalogEntry{appStable}, Num: 10}, []uint64{2}},
+			{"name and tag pairs", LogQuery{CatalogEntries: []CatalogEntry{appLatest, otherStable}, Num: 10}, []uint64{4, 1}},
+			{"unknown entry", LogQuery{CatalogEntries: []CatalogEntry{{Name: "app", Tag: "edge"}}, Num: 10}, nil},
+			{"empty set", LogQuery{CatalogEntries: []CatalogEntry{}, Num: 10}, nil},
+			{"paged", LogQuery{CatalogEntries: []CatalogEntry{appLatest, appStable, otherLatest}, Num: 2, Page: 1}, []uint64{1}},
+			{"with entry in set", LogQuery{CatalogEntry: &otherLatest, CatalogEntries: []CatalogEntry{appLatest, otherLatest}, Num: 10}, []uint64{3}},
+			{"with entry not in set", LogQuery{CatalogEntry: &otherLatest, CatalogEntries: []CatalogEntry{appLatest}, Num: 10}, nil},
+			{"with range", LogQuery{CatalogEntries: []CatalogEntry{appLatest, otherLatest}, Num: 10, Since: rt.Add(time.Minute)}, []uint64{3}},
+		} {
+			logs, err := dbc.Logs.Query(tc.query)
+			if err != nil {
+				t.Fatalf("%s: %s: querying log: %s", name, tc.name, err)
+			}
+
+			var got []uint64
+			for _, le := range logs {
+				got = append(got, le.ID)
+			}
+
+			if !slices.Equal(got, tc.expect) {
+				t.Errorf("%s: %s: expected IDs %v, got %v", name, tc.name, tc.expect, got)
+			}
+		}
+	}
+}
+
 func Test_LogStorage(t *testing.T) {
 	dbc, err := NewClient("sqlite3", sqlliteMemoryDSN)
 	if err != nil {
@@ -128,3 +213,60 @@ func Test_LogStorage(t *testing.T) {
 		t.Errorf("got unexpected number of logs: %d != 5", c)
 	}
 }
+
+func Test_LogQuery(t *testing.T) {
+	// Separate database as other tests count all log entries
+	dbc, err := NewClient("sqlite3", "file:logquery?mode=memory&cache=shared")
+	if err != nil {
+		t.Fatalf("unable to create database client: %s", err)
+	}
+	t.Cleanup(func() { _ = dbc.Close() })
+
+	var (
+		ce = CatalogEntry{Name: "testapp", Tag: "latest"}
+		rt = time.Now().UTC().Truncate(time.Hour)
+	)
+
+	// Two entries share a timestamp to ensure cursors are unambiguous
+	for _, ts := range []time.Time{rt.Add(-4 * time.Hour), rt.Add(-3 * time.Hour), rt.Add(-3 * time.Hour), rt.Add(-2 * time.Hour), rt.Add(-1 * time.Hour)} {
+		if err = dbc.Logs.Add(&LogEntry{CatalogName: ce.Name, CatalogTag: ce.Tag, Timestamp: ts}); err != nil {
+			t.Fatalf("unable to add log entry: %s", err)
+		}
+	}
+
+	ids := func(logs []LogEntry) (out []uint64) {
+		for _, le := range logs {
+			out = append(out, le.ID)
+		}
+		return out
+	}
+
+	for name, tc := range map[string]struct {
+		query  LogQuery
+		expect []uint64
+	}{
+		"first page":       {LogQuery{Num: 2}, []uint64{5, 4}},
+		"before cursor":    {LogQuery{Num: 2, Before: 4}, []uint64{3, 2}},
+		"before shared ts": {LogQuery{Num: 2, Before: 3}, []uint64{2, 1}},
+		"after cursor":     {LogQuery{Num: 2, After: 2}, []uint64{4, 3}},
+		"after shared ts":  {LogQuery{Num: 2, After: 1}, []uint64{3, 2}},
+		"since":            {LogQuery{Num: 10, Since: rt.Add(-3 * time.Hour)}, []uint64{5, 4, 3, 2}},
+		"until":            {LogQuery{Num: 10, Until: rt.Add(-2 * time.Hour)}, []uint64{3, 2, 1}},
+		"entry and range":  {LogQuery{CatalogEntry: &ce, Num: 10, Since: rt.Add(-2 * time.Hour), Until: rt}, []uint64{5, 4}},
+		"entry set":        {LogQuery{CatalogEntries: []CatalogEntry{{Name: "other"}, ce}, Num: 2}, []uint64{5, 4}},
+		"empty entry set":  {LogQuery{CatalogEntries: []CatalogEntry{}, Num: 2}, nil},
+	} {
+		logs, err := dbc.Logs.Query(tc.query)
+		if err != nil {
+			t.Fatalf("%s: querying log: %s", name, err)
+		}
+
+		if got := ids(logs); !slices.Equal(got, tc.expect) {
+			t.Errorf("%s: expected IDs %v, got %v", name, tc.expect, got)
+		}
+	}
+
+	if _, err = dbc.Logs.Query(LogQuery{Num: 2, Before: 100}); !errors.Is(err, ErrUnknownCursor) {
+		t.Errorf("expected ErrUnknownCursor for missing cursor, got %v", err)
+	}
+}
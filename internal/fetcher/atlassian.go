@@ -35,23 +35,38 @@ var (
 func init() { registerFetcher("atlassian", func() Fetcher { return &AtlassianFetcher{} }) }
 
 // FetchVersion retrieves the latest version for the catalog entry
-func (AtlassianFetcher) FetchVersion(ctx context.Context, attrs *fieldcollection.FieldCollection) (string, time.Time, error) {
+func (a AtlassianFetcher) FetchVersion(ctx context.Context, attrs *fieldcollection.FieldCollection) (string, time.Time, error) {
+	vers, err := a.FetchVersions(ctx, attrs)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	if len(vers) == 0 {
+		return "", time.Time{}, ErrNoVersionFound
+	}
+
+	return vers[0].Version, vers[0].Time, nil
+}
+
+// FetchVersions retrieves all versions matching edition and search
+// sorted by their release date, newest first
+func (AtlassianFetcher) FetchVersions(ctx context.Context, attrs *fieldcollection.FieldCollection) ([]VersionCandidate, error) {
 	url := fmt.Sprintf("https://my.atlassian.com/download/feeds/current/%s.json", attrs.MustString("product", nil))
 
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
 	if err != nil {
-		return "", time.Time{}, fmt.Errorf("creating request: %w", err)
+		return nil, fmt.Errorf("creating request: %w", err)
 	}
 
 	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
-		return "", time.Time{}, fmt.Errorf("executing request: %w", err)
+		return nil, fmt.Errorf("executing request: %w", err)
 	}
 	defer func() { helpers.LogIfErr(resp.Body.Close(), "closing response body after read") }()
 
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return "", time.Time{}, fmt.Errorf("reading response body: %w", err)
+		return nil, fmt.Errorf("reading response body: %w", err)
 	}
 
 	var payload []struct {
@@ -69,7 +84,7 @@ func (AtlassianFetcher) FetchVersion(ctx context.Context, attrs *fieldcollection
 	}
 
 	if err = json.Unmarshal(body, &payload); err != nil {
-		return "", time.Time{}, fmt.Errorf("parsing response JSON: %w", err)
+		return nil, fmt.Errorf("parsing response JSON: %w", err)
 	}
 
 	sort.Slice(payload, func(j, i int) bool { // j, i -> Reverse sort, biggest date at the top
@@ -83,6 +98,8 @@ func (AtlassianFetcher) FetchVersion(ctx context.Context, attrs *fieldcollection
 		edition = attrs.MustString("edition", &atlassianDefaultEdition)
 		// @attr search optional string "TAR.GZ" What to search in the download description: default is to search for the standalone .tar.gz file
 		search = attrs.MustString("search", &atlassianDefaultSearch)
+
+		vers []VersionCandidate
 	)
 
 	for _, r := range payload {
@@ -95,10 +112,10 @@ func (AtlassianFetcher) FetchVersion(ctx context.Context, attrs *fieldcollection
 		}
 
 		rt, _ := time.Parse("02-Jan-2006", r.Released)
-		return r.Version, rt, nil
+		vers = append(vers, VersionCandidate{Version: r.Version, Time: rt})
 	}
 
-	return "", time.Time{}, ErrNoVersionFound
+	return vers, nil
 }
 
 // Links retrieves a collection of links for the fetcher
@@ -28,10 +28,31 @@ type (
 func init() { registerFetcher("git_tag", func() Fetcher { return &GitTagFetcher{} }) }
 
 // FetchVersion retrieves the latest version for the catalog entry
-func (g GitTagFetcher) FetchVersion(_ context.Context, attrs *fieldcollection.FieldCollection) (string, time.Time, error) {
+func (g GitTagFetcher) FetchVersion(ctx context.Context, attrs *fieldcollection.FieldCollection) (string, time.Time, error) {
+	tags, err := g.FetchVersions(ctx, attrs)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	var latestTag *VersionCandidate
+	for i := range tags {
+		if latestTag == nil || tags[i].Time.After(latestTag.Time) {
+			latestTag = &tags[i]
+		}
+	}
+
+	if latestTag == nil {
+		return "", time.Time{}, ErrNoVersionFound
+	}
+
+	return latestTag.Version, latestTag.Time, nil
+}
+
+// FetchVersions retrieves all tags of the repository
+func (g GitTagFetcher) FetchVersions(ctx context.Context, attrs *fieldcollection.FieldCollection) ([]VersionCandidate, error) {
 	repo, err := git.Init(memory.NewStorage(), nil)
 	if err != nil {
-		return "", time.Time{}, fmt.Errorf("opening in-mem repo: %w", err)
+		return nil, fmt.Errorf("opening in-mem repo: %w", err)
 	}
 
 	_, err = repo.CreateRemote(&config.RemoteConfig{
@@ -39,47 +60,36 @@ func (g GitTagFetcher) FetchVersion(_ context.Context, attrs *fieldcollection.Fi
 		URLs: []string{attrs.MustString("remote", nil)},
 	})
 	if err != nil {
-		return "", time.Time{}, fmt.Errorf("adding remote: %w", err)
+		return nil, fmt.Errorf("adding remote: %w", err)
 	}
 
-	if err = repo.Fetch(&git.FetchOptions{
+	if err = repo.FetchContext(ctx, &git.FetchOptions{
 		Depth:      1,
 		RefSpecs:   []config.RefSpec{"+refs/tags/*:refs/remotes/origin/tags/*"},
 		RemoteName: "origin",
 	}); err != nil {
-		return "", time.Time{}, fmt.Errorf("fetching remote: %w", err)
+		return nil, fmt.Errorf("fetching remote: %w", err)
 	}
 
-	tags, err := repo.Tags()
+	tagRefs, err := repo.Tags()
 	if err != nil {
-		return "", time.Time{}, fmt.Errorf("listing tags: %w", err)
+		return nil, fmt.Errorf("listing tags: %w", err)
 	}
 
-	var (
-		latestTag     *plumbing.Reference
-		latestTagTime time.Time
-	)
-	if err = tags.ForEach(func(ref *plumbing.Reference) error {
+	var tags []VersionCandidate
+	if err = tagRefs.ForEach(func(ref *plumbing.Reference) error {
 		tt, err := g.tagRefToTime(repo, ref)
 		if err != nil {
 			return fmt.Errorf("fetching time for tag: %w", err)
 		}
 
-		if latestTag == nil || tt.After(latestTagTime) {
-			latestTag = ref
-			latestTagTime = tt
-		}
-
+		tags = append(tags, VersionCandidate{Version: ref.Name().Short(), Time: tt})
 		return nil
 	}); err != nil {
-		return "", time.Time{}, fmt.Errorf("iterating tags: %w", err)
-	}
-
-	if latestTag == nil {
-		return "", time.Time{}, ErrNoVersionFound
+		return nil, fmt.Errorf("iterating tags: %w", err)
 	}
 
-	return latestTag.Name().Short(), latestTagTime, nil
+	return tags, nil
 }
 
 // Links retrieves a collection of links for the fetcher
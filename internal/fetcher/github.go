@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"net/http"
 	"os"
+	"regexp"
 	"time"
 
 	"github.com/Luzifer/go_helpers/fieldcollection"
@@ -17,10 +18,16 @@ import (
 
 /*
  * @module github_release
- * @module_desc Fetches the latest release from Github for a given repository not marked as pre-release
+ * @module_desc Fetches the latest release from Github for a given repository not marked as pre-release out of the 100 most recent releases. When listing all versions for a `version_constraint` releases marked as pre-release are included (and only applied with `allow_prerelease`), at most the 500 most recent releases are listed.
  */
 
-const githubHTTPTimeout = 2 * time.Second
+const (
+	githubHTTPTimeout = 2 * time.Second
+	// githubMaxReleasePages limits the number of release pages fetched
+	// with githubReleasesPerPage releases each
+	githubMaxReleasePages = 5
+	githubReleasesPerPage = 100
+)
 
 type (
 	// GithubReleaseFetcher implements the fetcher interface to monitor releases in a Github repository
@@ -33,41 +40,20 @@ type (
 	}
 )
 
-func init() { registerFetcher("github_release", func() Fetcher { return &GithubReleaseFetcher{} }) }
-
-// FetchVersion retrieves the latest version for the catalog entry
-func (GithubReleaseFetcher) FetchVersion(ctx context.Context, attrs *fieldcollection.FieldCollection) (string, time.Time, error) {
-	ctx, cancel := context.WithTimeout(ctx, githubHTTPTimeout)
-	defer cancel()
+var (
+	githubAPIBase = "https://api.github.com"
 
-	req, err := http.NewRequestWithContext(
-		ctx,
-		http.MethodGet,
-		fmt.Sprintf("https://api.github.com/repos/%s/releases", attrs.MustString("repository", nil)),
-		nil,
-	)
-	if err != nil {
-		return "", time.Time{}, fmt.Errorf("creating http request: %w", err)
-	}
-	req.Header.Set("User-Agent", "Luzifer/go-latestver GithubReleaseFetcher")
+	githubNextLink = regexp.MustCompile(`<([^>]+)>;\s*rel="next"`)
+)
 
-	if os.Getenv("GITHUB_CLIENT_ID") != "" && os.Getenv("GITHUB_CLIENT_SECRET") != "" {
-		req.SetBasicAuth(os.Getenv("GITHUB_CLIENT_ID"), os.Getenv("GITHUB_CLIENT_SECRET"))
-	}
+func init() { registerFetcher("github_release", func() Fetcher { return &GithubReleaseFetcher{} }) }
 
-	resp, err := http.DefaultClient.Do(req)
+// FetchVersion retrieves the latest version for the catalog entry,
+// only the first page of releases is fetched
+func (g GithubReleaseFetcher) FetchVersion(ctx context.Context, attrs *fieldcollection.FieldCollection) (string, time.Time, error) {
+	payload, _, err := g.fetchReleasePage(ctx, g.releasesURL(attrs))
 	if err != nil {
-		return "", time.Time{}, fmt.Errorf("executing request: %w", err)
-	}
-	defer func() { helpers.LogIfErr(resp.Body.Close(), "closing response body after read") }()
-
-	if resp.StatusCode != http.StatusOK {
-		return "", time.Time{}, fmt.Errorf("unexpected HTTP status %d", resp.StatusCode)
-	}
-
-	var payload []githubRelease
-	if err = json.NewDecoder(resp.Body).Decode(&payload); err != nil {
-		return "", time.Time{}, fmt.Errorf("decoding response: %w", err)
+		return "", time.Time{}, err
 	}
 
 	var release *githubRelease
@@ -88,6 +74,30 @@ func (GithubReleaseFetcher) FetchVersion(ctx context.Context, attrs *fieldcollec
 	return release.TagName, release.PublishedAt, nil
 }
 
+// FetchVersions retrieves the releases including the ones marked as
+// pre-release, at most githubMaxReleasePages are fetched
+func (g GithubReleaseFetcher) FetchVersions(ctx context.Context, attrs *fieldcollection.FieldCollection) ([]VersionCandidate, error) {
+	var (
+		next     = g.releasesURL(attrs)
+		releases []VersionCandidate
+	)
+
+	for page := 0; next != "" && page < githubMaxReleasePages; page++ {
+		payload, nextPage, err := g.fetchReleasePage(ctx, next)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, r := range payload {
+			releases = append(releases, VersionCandidate{Version: r.TagName, Time: r.PublishedAt, Prerelease: r.Prerelease})
+		}
+
+		next = nextPage
+	}
+
+	return releases, nil
+}
+
 // Links retrieves a collection of links for the fetcher
 func (GithubReleaseFetcher) Links(attrs *fieldcollection.FieldCollection) []database.CatalogLink {
 	return []database.CatalogLink{
@@ -108,3 +118,47 @@ func (GithubReleaseFetcher) Validate(attrs *fieldcollection.FieldCollection) err
 
 	return nil
 }
+
+// fetchReleasePage retrieves one page of releases and the URL of the
+// next page if there is one
+func (GithubReleaseFetcher) fetchReleasePage(ctx context.Context, pageURL string) ([]githubRelease, string, error) {
+	ctx, cancel := context.WithTimeout(ctx, githubHTTPTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, pageURL, nil)
+	if err != nil {
+		return nil, "", fmt.Errorf("creating http request: %w", err)
+	}
+	req.Header.Set("User-Agent", "Luzifer/go-latestver GithubReleaseFetcher")
+
+	if os.Getenv("GITHUB_CLIENT_ID") != "" && os.Getenv("GITHUB_CLIENT_SECRET") != "" {
+		req.SetBasicAuth(os.Getenv("GITHUB_CLIENT_ID"), os.Getenv("GITHUB_CLIENT_SECRET"))
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, "", fmt.Errorf("executing request: %w", err)
+	}
+	defer func() { helpers.LogIfErr(resp.Body.Close(), "closing response body after read") }()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", fmt.Errorf("unexpected HTTP status %d", resp.StatusCode)
+	}
+
+	var payload []githubRelease
+	if err = json.NewDecoder(resp.Body).Decode(&payload); err != nil {
+		return nil, "", fmt.Errorf("decoding response: %w", err)
+	}
+
+	var next string
+	if m := githubNextLink.FindStringSubmatch(resp.Header.Get("Link")); m != nil {
+		next = m[1]
+	}
+
+	return payload, next, nil
+}
+
+// releasesURL returns the URL of the first page of releases
+func (GithubReleaseFetcher) releasesURL(attrs *fieldcollection.FieldCollection) string {
+	return fmt.Sprintf("%s/repos/%s/releases?per_page=%d", githubAPIBase, attrs.MustString("repository", nil), githubReleasesPerPage)
+}
@@ -2,6 +2,9 @@ package fetcher
 
 import (
 	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 
 	"github.com/Luzifer/go_helpers/fieldcollection"
@@ -43,3 +46,49 @@ func Test_GithubReleaseFetcherInvalid(t *testing.T) {
 		t.Fatalf("fetching version dit not cause error")
 	}
 }
+
+func Test_GithubReleaseFetcherPages(t *testing.T) {
+	var (
+		requests int
+		srv      *httptest.Server
+	)
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.URL.Query().Get("page") == "" {
+			w.Header().Set("Link", fmt.Sprintf(`<%s%s?per_page=100&page=2>; rel="next", <%s%s?per_page=100&page=2>; rel="last"`, srv.URL, r.URL.Path, srv.URL, r.URL.Path))
+			w.Write([]byte(`[{"tag_name":"v2.0.0-rc1","published_at":"2024-03-01T00:00:00Z","prerelease":true},{"tag_name":"v1.1.0","published_at":"2024-02-01T00:00:00Z"}]`)) //nolint:errcheck,gosec // test server
+			return
+		}
+
+		w.Write([]byte(`[{"tag_name":"v1.0.0","published_at":"2024-01-01T00:00:00Z"}]`)) //nolint:errcheck,gosec // test server
+	}))
+	defer srv.Close()
+
+	defer func(base string) { githubAPIBase = base }(githubAPIBase)
+	githubAPIBase = srv.URL
+
+	attrs := fieldcollection.FromData(map[string]any{"repository": "owner/repo"})
+
+	vers, err := GithubReleaseFetcher{}.FetchVersions(context.Background(), attrs)
+	if err != nil {
+		t.Fatalf("fetching versions: %s", err)
+	}
+
+	if len(vers) != 3 || !vers[0].Prerelease || vers[1].Prerelease || vers[2].Version != "v1.0.0" {
+		t.Errorf("got unexpected versions: %+v", vers)
+	}
+
+	requests = 0
+	ver, _, err := GithubReleaseFetcher{}.FetchVersion(context.Background(), attrs)
+	if err != nil {
+		t.Fatalf("fetching version: %s", err)
+	}
+
+	if ver != "v1.1.0" {
+		t.Errorf("expected latest non-prerelease version, got %s", ver)
+	}
+
+	if requests != 1 {
+		t.Errorf("expected latest version to be fetched using one request, got %d", requests)
+	}
+}
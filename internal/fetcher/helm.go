@@ -48,6 +48,25 @@ func (h HELMFetcher) FetchVersion(ctx context.Context, attrs *fieldcollection.Fi
 	return vers[0].Version, vers[0].Created, nil
 }
 
+// FetchVersions retrieves all versions of the chart listed in the repo
+func (h HELMFetcher) FetchVersions(ctx context.Context, attrs *fieldcollection.FieldCollection) ([]VersionCandidate, error) {
+	vers, err := h.getChartVersionsFromRepo(ctx, attrs.MustString("repo", nil), attrs.MustString("chart", nil))
+	if err != nil {
+		return nil, fmt.Errorf("fetching chart versions: %w", err)
+	}
+
+	if vers == nil {
+		return nil, fmt.Errorf("chart not found in repo")
+	}
+
+	out := make([]VersionCandidate, 0, len(vers))
+	for _, v := range vers {
+		out = append(out, VersionCandidate{Version: v.Version, Time: v.Created})
+	}
+
+	return out, nil
+}
+
 // Links retrieves a collection of links for the fetcher
 func (HELMFetcher) Links(_ *fieldcollection.FieldCollection) []database.CatalogLink { return nil }
 
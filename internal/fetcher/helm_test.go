@@ -0,0 +1,68 @@
+package fetcher
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Luzifer/go_helpers/fieldcollection"
+)
+
+const helmTestIndex = `apiVersion: v1
+entries:
+  testchart:
+    - apiVersion: v2
+      name: testchart
+      version: 1.1.0
+      created: "2024-02-01T00:00:00Z"
+    - apiVersion: v2
+      name: testchart
+      version: 1.2.0-rc.1
+      created: "2024-03-01T00:00:00Z"
+    - apiVersion: v2
+      name: testchart
+      version: 1.0.0
+      created: "2024-01-01T00:00:00Z"
+`
+
+func Test_HELMFetcherVersions(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Write([]byte(helmTestIndex)) //nolint:errcheck,gosec // test server
+	}))
+	defer srv.Close()
+
+	attrs := fieldcollection.FromData(map[string]any{
+		"repo":  srv.URL,
+		"chart": "testchart",
+	})
+
+	f := Get("helm")
+
+	if err := f.Validate(attrs); err != nil {
+		t.Fatalf("validating attributes: %s", err)
+	}
+
+	vf, ok := f.(VersionsFetcher)
+	if !ok {
+		t.Fatal("helm fetcher does not implement VersionsFetcher")
+	}
+
+	vers, err := vf.FetchVersions(context.Background(), attrs)
+	if err != nil {
+		t.Fatalf("fetching versions: %s", err)
+	}
+
+	if c := len(vers); c != 3 {
+		t.Errorf("got unexpected number of versions: %d != 3", c)
+	}
+
+	ver, _, err := f.FetchVersion(context.Background(), attrs)
+	if err != nil {
+		t.Fatalf("fetching version: %s", err)
+	}
+
+	if ver != "1.2.0-rc.1" {
+		t.Errorf("got unexpected latest version: %s", ver)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"net/http"
 	"regexp"
 	"time"
 
@@ -11,8 +12,10 @@ import (
 	"github.com/antchfx/htmlquery"
 	"github.com/antchfx/xpath"
 	"golang.org/x/net/html"
+	"golang.org/x/net/html/charset"
 
 	"github.com/Luzifer/go-latestver/internal/database"
+	"github.com/Luzifer/go-latestver/internal/helpers"
 )
 
 /*
@@ -30,10 +33,26 @@ var htmlFetcherDefaultRegex = `(v?(?:[0-9]+\.?){2,})`
 func init() { registerFetcher("html", func() Fetcher { return &HTMLFetcher{} }) }
 
 // FetchVersion retrieves the latest version for the catalog entry
-func (HTMLFetcher) FetchVersion(_ context.Context, attrs *fieldcollection.FieldCollection) (string, time.Time, error) {
-	doc, err := htmlquery.LoadURL(attrs.MustString("url", nil))
+func (HTMLFetcher) FetchVersion(ctx context.Context, attrs *fieldcollection.FieldCollection) (string, time.Time, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, attrs.MustString("url", nil), nil)
 	if err != nil {
-		return "", time.Time{}, fmt.Errorf("loading URL: %w", err)
+		return "", time.Time{}, fmt.Errorf("creating request: %w", err)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("executing request: %w", err)
+	}
+	defer func() { helpers.LogIfErr(resp.Body.Close(), "closing response body after read") }()
+
+	body, err := charset.NewReader(resp.Body, resp.Header.Get("Content-Type"))
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("detecting charset: %w", err)
+	}
+
+	doc, err := htmlquery.Parse(body)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("parsing HTML document: %w", err)
 	}
 
 	node, err := htmlquery.Query(doc, attrs.MustString("xpath", nil))
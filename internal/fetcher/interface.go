@@ -23,6 +23,23 @@ type (
 		// Validate validates the configuration given to the fetcher
 		Validate(attrs *fieldcollection.FieldCollection) error
 	}
+	// VersionsFetcher is an optional interface for fetchers able to
+	// retrieve all candidate versions instead of only the latest one
+	VersionsFetcher interface {
+		// FetchVersions retrieves all candidate versions for the catalog entry
+		FetchVersions(ctx context.Context, attrs *fieldcollection.FieldCollection) ([]VersionCandidate, error)
+	}
+
+	// VersionCandidate represents a single version returned by a
+	// VersionsFetcher together with its release time
+	VersionCandidate struct {
+		Version string
+		Time    time.Time
+		// Prerelease is set when the source marks the version as
+		// pre-release regardless of the version format
+		Prerelease bool
+	}
+
 	// Create represents a function to instantiate a Fetcher
 	Create func() Fetcher
 )
@@ -38,46 +38,35 @@ func init() { registerFetcher("json", func() Fetcher { return &JSONFetcher{} })
 
 // FetchVersion retrieves the latest version for the catalog entry
 func (JSONFetcher) FetchVersion(ctx context.Context, attrs *fieldcollection.FieldCollection) (string, time.Time, error) {
-	var (
-		doc *jsonquery.Node
-		err error
-	)
-
-	// @attr jsonp optional boolean "false" File contains JSONP function, strip it to get the raw JSON
-	if attrs.MustBool("jsonp", ptrBoolFalse) {
-		var (
-			body []byte
-			req  *http.Request
-			resp *http.Response
-		)
-		req, err = http.NewRequestWithContext(ctx, http.MethodGet, attrs.MustString("url", nil), nil)
-		if err != nil {
-			return "", time.Time{}, fmt.Errorf("creating request: %w", err)
-		}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, attrs.MustString("url", nil), nil)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("creating request: %w", err)
+	}
 
-		resp, err = http.DefaultClient.Do(req)
-		if err != nil {
-			return "", time.Time{}, fmt.Errorf("executing request: %w", err)
-		}
-		defer func() { helpers.LogIfErr(resp.Body.Close(), "closing response body after read") }()
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("executing request: %w", err)
+	}
+	defer func() { helpers.LogIfErr(resp.Body.Close(), "closing response body after read") }()
 
-		body, err = io.ReadAll(resp.Body)
-		if err != nil {
-			return "", time.Time{}, fmt.Errorf("reading response body: %w", err)
-		}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("reading response body: %w", err)
+	}
 
+	// @attr jsonp optional boolean "false" File contains JSONP function, strip it to get the raw JSON
+	if attrs.MustBool("jsonp", ptrBoolFalse) {
 		matches := jsonpStripRegex.FindSubmatch(body)
 		if matches == nil {
 			return "", time.Time{}, errors.New("document does not match jsonp syntax")
 		}
 
-		doc, err = jsonquery.Parse(bytes.NewReader(matches[1]))
-	} else {
-		doc, err = jsonquery.LoadURL(attrs.MustString("url", nil))
+		body = matches[1]
 	}
 
+	doc, err := jsonquery.Parse(bytes.NewReader(body))
 	if err != nil {
-		return "", time.Time{}, errors.New("parsing JSON document")
+		return "", time.Time{}, fmt.Errorf("parsing JSON document: %w", err)
 	}
 
 	node, err := jsonquery.Query(doc, attrs.MustString("xpath", nil))
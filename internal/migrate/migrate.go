@@ -11,6 +11,9 @@ import (
 )
 
 var cfg = struct {
+	BatchSize      int    `flag:"batch-size" default:"1000" description:"Number of rows to read and write at once"`
+	DryRun         bool   `flag:"dry-run" default:"false" description:"Only print the number of rows in the 'from' storage without comparing them to the 'to' storage"`
+	Verify         bool   `flag:"verify" default:"false" description:"Compare row counts and checksums of both databases instead of migrating"`
 	FromStorage    string `flag:"from-storage" description:"Storage type to migrate from" validate:"nonzero"`   //revive:disable-line:struct-tag // nonzero is valid for our validate
 	FromStorageDSN string `flag:"from-storage-dsn" description:"DSN for the 'from' storage" validate:"nonzero"` //revive:disable-line:struct-tag // nonzero is valid for our validate
 	ToStorage      string `flag:"to-storage" description:"Storage type to migrate to" validate:"nonzero"`       //revive:disable-line:struct-tag // nonzero is valid for our validate
@@ -37,14 +40,70 @@ func main() {
 		logrus.WithError(err).Fatal("opening from database")
 	}
 
+	if cfg.DryRun {
+		res, err := src.Migrate(nil, database.MigrateOptions{BatchSize: cfg.BatchSize, DryRun: true})
+		if err != nil {
+			logrus.WithError(err).Fatal("counting rows")
+		}
+
+		for _, r := range res {
+			logrus.WithField("rows", r.Rows).Infof("found %s in source, rows already present in the destination are included", r.Table)
+		}
+		return
+	}
+
 	dest, err := database.NewClient(cfg.ToStorage, cfg.ToStorageDSN)
 	if err != nil {
 		logrus.WithError(err).Fatal("opening to database")
 	}
 
-	if err := src.Migrate(dest); err != nil {
+	if cfg.Verify {
+		verify(src, dest)
+		return
+	}
+
+	res, err := src.Migrate(dest, database.MigrateOptions{BatchSize: cfg.BatchSize})
+	if err != nil {
 		logrus.WithError(err).Fatal("execute migration")
 	}
 
+	for _, r := range res {
+		logrus.WithFields(logrus.Fields{
+			"rows":    r.Rows,
+			"skipped": r.Skipped,
+		}).Infof("migrated %s", r.Table)
+	}
+
 	logrus.Info("your database has been migrated")
 }
+
+func verify(src, dest *database.Client) {
+	res, err := src.Verify(dest, cfg.BatchSize)
+	if err != nil {
+		logrus.WithError(err).Fatal("verifying migration")
+	}
+
+	var mismatch bool
+	for _, r := range res {
+		logger := logrus.WithFields(logrus.Fields{
+			"source_rows":     r.SourceRows,
+			"source_checksum": r.SourceChecksum,
+			"dest_rows":       r.DestRows,
+			"dest_checksum":   r.DestChecksum,
+		})
+
+		if !r.Match() {
+			logger.Errorf("%s differs", r.Table)
+			mismatch = true
+			continue
+		}
+
+		logger.Infof("%s matches", r.Table)
+	}
+
+	if mismatch {
+		logrus.Fatal("databases differ")
+	}
+
+	logrus.Info("your database has been verified")
+}
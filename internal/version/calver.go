@@ -0,0 +1,98 @@
+package version
+
+import (
+	"cmp"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+const (
+	calVerCentury       = 2000
+	calVerShortYearSize = 2
+)
+
+type (
+	calVerComparer struct{}
+
+	calVerVersion struct {
+		Segments []int
+		Modifier string
+	}
+)
+
+var (
+	_ comparer = calVerComparer{}
+
+	calVerRegex = regexp.MustCompile(`^(\d{2}|\d{4})((?:\.\d+)*)(?:[-_.+]?([A-Za-z][A-Za-z0-9.-]*))?$`)
+)
+
+func (c calVerComparer) Compare(oldVersion, newVersion string) (compareResult, error) {
+	oldV, err := c.parse(oldVersion)
+	if err != nil {
+		return compareResultInvalid, fmt.Errorf("parsing old version: %w", err)
+	}
+
+	newV, err := c.parse(newVersion)
+	if err != nil {
+		return compareResultInvalid, fmt.Errorf("parsing new version: %w", err)
+	}
+
+	if res := slices.Compare(oldV.Segments, newV.Segments); res != 0 {
+		return compareResultFromCmp(res), nil
+	}
+
+	oldRank, oldNum := parseModifier(oldV.Modifier)
+	newRank, newNum := parseModifier(newV.Modifier)
+
+	if res := cmp.Compare(oldRank, newRank); res != 0 {
+		return compareResultFromCmp(res), nil
+	}
+
+	return compareResultFromCmp(cmp.Compare(oldNum, newNum)), nil
+}
+
+func (c calVerComparer) IsPrerelease(newVersion string) (bool, error) {
+	v, err := c.parse(newVersion)
+	if err != nil {
+		return false, fmt.Errorf("parsing version: %w", err)
+	}
+
+	rank, _ := parseModifier(v.Modifier)
+	return rank < prereleaseRankFinal, nil
+}
+
+func (calVerComparer) parse(ver string) (calVerVersion, error) {
+	var v calVerVersion
+
+	match := calVerRegex.FindStringSubmatch(ver)
+	if match == nil {
+		return v, fmt.Errorf("version %q does not start with a year", ver)
+	}
+
+	year, _ := strconv.Atoi(match[1]) // Regex ensures digits
+	if len(match[1]) == calVerShortYearSize {
+		// Short years (YY) are normalized to be comparable to full years
+		year += calVerCentury
+	}
+	v.Segments = append(v.Segments, year)
+
+	for seg := range strings.SplitSeq(strings.TrimPrefix(match[2], "."), ".") {
+		if seg == "" {
+			continue
+		}
+		n, _ := strconv.Atoi(seg) // Regex ensures digits
+		v.Segments = append(v.Segments, n)
+	}
+
+	for len(v.Segments) > 1 && v.Segments[len(v.Segments)-1] == 0 {
+		// Trailing zeros are not significant: 24.0 == 24.0.0
+		v.Segments = v.Segments[:len(v.Segments)-1]
+	}
+
+	v.Modifier = match[3]
+
+	return v, nil
+}
@@ -0,0 +1,31 @@
+package version
+
+import "testing"
+
+func TestCalVerCompareFunc(t *testing.T) {
+	comp := calVerComparer{}
+
+	for _, tc := range []struct {
+		v1, v2 string
+		res    compareResult
+	}{
+		{"2024.01.15", "2024.02.01", compareResultUpgrade},
+		{"24.04", "24.10", compareResultUpgrade},
+		{"24.10", "2025.04", compareResultUpgrade},
+		{"2024.1", "2024.01", compareResultEqual},
+		{"24.0", "24.0.1", compareResultUpgrade},
+		{"2024.1.0rc1", "2024.1.0", compareResultUpgrade},
+		{"2024.1.0.dev0", "2024.1.0b1", compareResultUpgrade},
+		{"2024.1.0", "2024.1.0.post1", compareResultUpgrade},
+		{"2024.12.31", "2024.2.1", compareResultDowngrade},
+	} {
+		res, err := comp.Compare(tc.v1, tc.v2)
+		if err != nil {
+			t.Errorf("Comparing %q to %q: %s", tc.v1, tc.v2, err)
+		}
+
+		if res != tc.res {
+			t.Errorf("Comparing %q to %q: expected %v, got %v", tc.v1, tc.v2, tc.res, res)
+		}
+	}
+}
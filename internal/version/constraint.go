@@ -5,6 +5,14 @@ import (
 	"fmt"
 )
 
+// Reasons for a version not being applied
+const (
+	BlockReasonNone       BlockReason = ""
+	BlockReasonDowngrade  BlockReason = "downgrade"
+	BlockReasonOutOfRange BlockReason = "out_of_range"
+	BlockReasonPrerelease BlockReason = "prerelease"
+)
+
 const (
 	compareResultInvalid compareResult = iota
 	compareResultEqual
@@ -13,12 +21,17 @@ const (
 )
 
 type (
+	// BlockReason describes why the Constraint prevents a version from
+	// being applied
+	BlockReason string
+
 	// Constraint document how a version update should be handled
 	Constraint struct {
 		AllowDowngrade  bool `yaml:"allow_downgrade"`
 		AllowPrerelease bool `yaml:"allow_prerelease"`
 
-		Type string `yaml:"type"`
+		Range string `yaml:"range"`
+		Type  string `yaml:"type"`
 	}
 
 	comparer interface {
@@ -29,51 +42,253 @@ type (
 	compareResult uint
 )
 
-// ShouldApply checks whether a new version should overwrite the old
-// one given the parameters inside the Constraint
-func (c Constraint) ShouldApply(oldVersion, newVersion string) (bool, error) {
-	if oldVersion == "" && newVersion != "" {
-		// The old version does not exist, the new one does, update it!
-		return true, nil
-	}
-
+// Evaluate checks whether the new version should be applied over the
+// old version and returns the reason if it must not be applied.
+// BlockReasonNone signals the version should be applied.
+func (c Constraint) Evaluate(oldVersion, newVersion string) (BlockReason, error) {
 	comp := c.getComparer()
 	if comp == nil {
-		return false, errors.New("invalid version type specified")
+		return BlockReasonNone, errors.New("invalid version type specified")
+	}
+
+	// Versions outside the range are ignored completely
+	inRange, err := c.inRange(comp, newVersion)
+	if err != nil {
+		return BlockReasonNone, fmt.Errorf("checking range: %w", err)
+	}
+
+	if !inRange {
+		return BlockReasonOutOfRange, nil
+	}
+
+	if oldVersion == "" && newVersion != "" {
+		// The old version does not exist, the new one does, update it!
+		return BlockReasonNone, nil
 	}
 
 	// Compare versions and check for UpgradeOnly flag
 	compResult, err := comp.Compare(oldVersion, newVersion)
 	if err != nil {
-		return false, fmt.Errorf("comparing versions: %w", err)
+		return BlockReasonNone, fmt.Errorf("comparing versions: %w", err)
 	}
 
 	if !c.AllowDowngrade && compResult != compareResultUpgrade {
-		return false, nil
+		return BlockReasonDowngrade, nil
 	}
 
 	// check for forbidden pre-releases
 	isPreR, err := comp.IsPrerelease(newVersion)
 	if err != nil {
-		return false, fmt.Errorf("checking pre-release: %w", err)
+		return BlockReasonNone, fmt.Errorf("checking pre-release: %w", err)
 	}
 
 	if !c.AllowPrerelease && isPreR {
-		return false, nil
+		return BlockReasonPrerelease, nil
+	}
+
+	return BlockReasonNone, nil
+}
+
+// Highest returns the highest of the candidate versions inside the
+// Range regardless of pre-release restrictions. Candidates not
+// parseable for the Type are ignored. If no candidate is valid an
+// empty string is returned.
+func (c Constraint) Highest(candidates []string) string {
+	comp := c.getComparer()
+	if comp == nil {
+		return ""
+	}
+
+	var best string
+	for _, cand := range candidates {
+		if _, err := comp.IsPrerelease(cand); err != nil {
+			// Candidate is not a valid version of this type
+			continue
+		}
+
+		if inRange, err := c.inRange(comp, cand); err != nil || !inRange {
+			// Versions outside the range are ignored completely
+			continue
+		}
+
+		if best != "" {
+			if res, err := comp.Compare(best, cand); err != nil || res != compareResultUpgrade {
+				continue
+			}
+		}
+
+		best = cand
+	}
+
+	return best
+}
+
+// PrereleaseIgnored reports whether AllowPrerelease is set for a Type
+// without pre-release markers and therefore has no effect
+func (c Constraint) PrereleaseIgnored() bool {
+	_, ok := c.getComparer().(numericDotSeparatedComparer)
+	return ok && c.AllowPrerelease
+}
+
+// Select picks the highest of the candidate versions allowed by the
+// Constraint and returns it if it should be applied over the old
+// version. Candidates not parseable for the Type are ignored. If no
+// candidate should be applied an empty string is returned.
+func (c Constraint) Select(oldVersion string, candidates []string) (string, error) {
+	comp := c.getComparer()
+	if comp == nil {
+		return "", errors.New("invalid version type specified")
+	}
+
+	var best string
+	for _, cand := range candidates {
+		isPreR, err := comp.IsPrerelease(cand)
+		if err != nil {
+			// Candidate is not a valid version of this type
+			continue
+		}
+
+		if isPreR && !c.AllowPrerelease {
+			continue
+		}
+
+		if inRange, err := c.inRange(comp, cand); err != nil || !inRange {
+			continue
+		}
+
+		if best != "" {
+			if res, err := comp.Compare(best, cand); err != nil || res != compareResultUpgrade {
+				continue
+			}
+		}
+
+		best = cand
+	}
+
+	if best == "" {
+		return "", nil
 	}
 
-	return true, nil
+	apply, err := c.ShouldApply(oldVersion, best)
+	if err != nil || !apply {
+		return "", err
+	}
+
+	return best, nil
+}
+
+// ShouldApply checks whether a new version should overwrite the old
+// one given the parameters inside the Constraint
+func (c Constraint) ShouldApply(oldVersion, newVersion string) (bool, error) {
+	reason, err := c.Evaluate(oldVersion, newVersion)
+	return err == nil && reason == BlockReasonNone, err
+}
+
+// Validate checks whether the Constraint has a known Type and its
+// Range can be parsed and applied to versions of that Type: ranges
+// containing an alternative no version can satisfy (">=2.0 <1.0") are
+// rejected
+func (c Constraint) Validate() error {
+	if c.Type == "" {
+		return errors.New("type is required")
+	}
+
+	comp := c.getComparer()
+	if comp == nil {
+		return fmt.Errorf("unknown version type %q", c.Type)
+	}
+
+	if c.Range == "" {
+		return nil
+	}
+
+	r, err := parseRange(c.Range, c.rangeSegments())
+	if err != nil {
+		return fmt.Errorf("parsing range: %w", err)
+	}
+
+	for _, group := range r {
+		for _, clause := range group {
+			if _, err = comp.Compare(clause.version, clause.version); err != nil {
+				return fmt.Errorf("range version %q is invalid for type %q: %w", clause.version, c.Type, err)
+			}
+		}
+
+		if !rangeGroupSatisfiable(comp, group) {
+			return fmt.Errorf("range %q contains an alternative no version can satisfy", c.Range)
+		}
+	}
+
+	return nil
 }
 
 func (c Constraint) getComparer() comparer {
 	switch c.Type {
+	case "calver":
+		return calVerComparer{}
+
+	case "debian":
+		return debianComparer{}
+
+	case "maven":
+		return mavenComparer{}
+
 	case "numeric_dot":
 		return numericDotSeparatedComparer{}
 
+	case "pep440":
+		return pep440Comparer{}
+
+	case "rpm":
+		return rpmComparer{}
+
 	case "semver":
 		return semVerComparer{}
 
+	case "semver_loose":
+		return semVerLooseComparer{}
+
 	default:
 		return nil
 	}
 }
+
+func (c Constraint) inRange(comp comparer, ver string) (bool, error) {
+	if c.Range == "" {
+		return true, nil
+	}
+
+	r, err := parseRange(c.Range, c.rangeSegments())
+	if err != nil {
+		return false, fmt.Errorf("parsing range: %w", err)
+	}
+
+	return r.contains(comp, ver)
+}
+
+// rangeSegments returns the number of segments numeric range bounds
+// are padded to: semver needs all three segments while types like
+// debian or rpm order "1.2" before "1.2.0" and must get the bound as
+// written
+func (c Constraint) rangeSegments() int {
+	switch c.Type {
+	case "semver", "semver_loose":
+		return semVerRangeSegments
+
+	default:
+		return 0
+	}
+}
+
+// compareResultFromCmp converts the result of a three-way comparison
+// of the old to the new version into a compareResult
+func compareResultFromCmp(cmp int) compareResult {
+	switch {
+	case cmp < 0:
+		return compareResultUpgrade
+	case cmp > 0:
+		return compareResultDowngrade
+	default:
+		return compareResultEqual
+	}
+}
@@ -0,0 +1,117 @@
+package version
+
+import "testing"
+
+func TestConstraintEvaluate(t *testing.T) {
+	for _, tc := range []struct {
+		name       string
+		constraint Constraint
+		oldV, newV string
+		expect     BlockReason
+	}{
+		{"upgrade", Constraint{Type: "semver"}, "1.0.0", "1.1.0", BlockReasonNone},
+		{"initial version", Constraint{Type: "semver"}, "", "1.1.0", BlockReasonNone},
+		{"downgrade", Constraint{Type: "semver"}, "1.1.0", "1.0.0", BlockReasonDowngrade},
+		{"allowed downgrade", Constraint{Type: "semver", AllowDowngrade: true}, "1.1.0", "1.0.0", BlockReasonNone},
+		{"prerelease", Constraint{Type: "semver"}, "1.3.0", "1.4.0-rc2", BlockReasonPrerelease},
+		{"allowed prerelease", Constraint{Type: "semver", AllowPrerelease: true}, "1.3.0", "1.4.0-rc2", BlockReasonNone},
+		{"out of range", Constraint{Type: "semver", Range: "^1"}, "1.3.0", "2.0.0", BlockReasonOutOfRange},
+	} {
+		reason, err := tc.constraint.Evaluate(tc.oldV, tc.newV)
+		if err != nil {
+			t.Errorf("%s: evaluating version: %s", tc.name, err)
+			continue
+		}
+
+		if reason != tc.expect {
+			t.Errorf("%s: expected reason %q, got %q", tc.name, tc.expect, reason)
+		}
+	}
+}
+
+func TestConstraintHighest(t *testing.T) {
+	c := Constraint{Type: "semver"}
+
+	if h := c.Highest([]string{"1.1.0", "1.3.0-rc1", "1.2.0", "latest"}); h != "1.3.0-rc1" {
+		t.Errorf("expected highest version 1.3.0-rc1, got %q", h)
+	}
+
+	if h := c.Highest([]string{"latest", "stable"}); h != "" {
+		t.Errorf("expected no highest version, got %q", h)
+	}
+
+	c.Range = "~1.1"
+	if h := c.Highest([]string{"1.1.0", "1.3.0-rc1", "1.2.0", "1.1.2-rc1"}); h != "1.1.2-rc1" {
+		t.Errorf("expected highest version in range 1.1.2-rc1, got %q", h)
+	}
+
+	if h := c.Highest([]string{"1.2.0", "1.3.0"}); h != "" {
+		t.Errorf("expected no highest version in range, got %q", h)
+	}
+}
+
+func TestConstraintSelect(t *testing.T) {
+	candidates := []string{"1.1.0", "1.3.0-rc1", "1.2.0", "latest", "0.9.0"}
+
+	for _, tc := range []struct {
+		name       string
+		constraint Constraint
+		old        string
+		expect     string
+	}{
+		{"highest stable", Constraint{Type: "semver"}, "1.0.0", "1.2.0"},
+		{"highest with prerelease", Constraint{Type: "semver", AllowPrerelease: true}, "1.0.0", "1.3.0-rc1"},
+		{"initial version", Constraint{Type: "semver"}, "", "1.2.0"},
+		{"already latest", Constraint{Type: "semver"}, "1.2.0", ""},
+		{"no upgrade available", Constraint{Type: "semver"}, "2.0.0", ""},
+		{"downgrade allowed", Constraint{Type: "semver", AllowDowngrade: true}, "2.0.0", "1.2.0"},
+	} {
+		ver, err := tc.constraint.Select(tc.old, candidates)
+		if err != nil {
+			t.Errorf("%s: selecting version: %s", tc.name, err)
+			continue
+		}
+
+		if ver != tc.expect {
+			t.Errorf("%s: expected %q, got %q", tc.name, tc.expect, ver)
+		}
+	}
+}
+
+func TestConstraintSelectInvalidType(t *testing.T) {
+	if _, err := (Constraint{Type: "unknown"}).Select("1.0.0", []string{"1.1.0"}); err == nil {
+		t.Error("selecting with invalid type did not cause error")
+	}
+}
+
+func TestConstraintValidate(t *testing.T) {
+	for _, tc := range []struct {
+		name       string
+		constraint Constraint
+		valid      bool
+	}{
+		{"semver", Constraint{Type: "semver"}, true},
+		{"semver with range", Constraint{Type: "semver", Range: "~1.28"}, true},
+		{"missing type", Constraint{AllowPrerelease: true}, false},
+		{"unknown type", Constraint{Type: "unknown"}, false},
+		{"unparseable range", Constraint{Type: "semver", Range: ">="}, false},
+		{"range invalid for type", Constraint{Type: "calver", Range: ">=1.0"}, false},
+		{"prerelease without markers", Constraint{Type: "numeric_dot", AllowPrerelease: true}, true},
+		{"prerelease with markers", Constraint{Type: "pep440", AllowPrerelease: true}, true},
+		{"empty range", Constraint{Type: "semver", Range: ">=2.0 <1.0"}, false},
+		{"empty exclusive range", Constraint{Type: "semver", Range: ">1.0 <=1.0"}, false},
+		{"single version range", Constraint{Type: "semver", Range: ">=1.0 <=1.0"}, true},
+		{"empty alternative", Constraint{Type: "semver", Range: "^1 || =3.0 <2.0"}, false},
+		{"debian range", Constraint{Type: "debian", Range: "~1.2"}, true},
+		{"compatible release", Constraint{Type: "pep440", Range: "~=2.2"}, true},
+		{"compatible release with one segment", Constraint{Type: "pep440", Range: "~=2"}, false},
+	} {
+		err := tc.constraint.Validate()
+		if tc.valid && err != nil {
+			t.Errorf("%s: expected valid constraint, got %s", tc.name, err)
+		}
+		if !tc.valid && err == nil {
+			t.Errorf("%s: expected validation error", tc.name)
+		}
+	}
+}
@@ -0,0 +1,147 @@
+package version
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+type (
+	debianComparer struct{}
+
+	debianVersion struct {
+		Epoch    int
+		Upstream string
+		Revision string
+	}
+)
+
+var (
+	_ comparer = debianComparer{}
+
+	debianRevisionRegex = regexp.MustCompile(`^[A-Za-z0-9.+~]+$`)
+	debianUpstreamRegex = regexp.MustCompile(`^[0-9][A-Za-z0-9.+~-]*$`)
+)
+
+func (d debianComparer) Compare(oldVersion, newVersion string) (compareResult, error) {
+	oldV, err := d.parse(oldVersion)
+	if err != nil {
+		return compareResultInvalid, fmt.Errorf("parsing old version: %w", err)
+	}
+
+	newV, err := d.parse(newVersion)
+	if err != nil {
+		return compareResultInvalid, fmt.Errorf("parsing new version: %w", err)
+	}
+
+	if oldV.Epoch != newV.Epoch {
+		return compareResultFromCmp(oldV.Epoch - newV.Epoch), nil
+	}
+
+	if cmp := debianVerRevCmp(oldV.Upstream, newV.Upstream); cmp != 0 {
+		return compareResultFromCmp(cmp), nil
+	}
+
+	return compareResultFromCmp(debianVerRevCmp(oldV.Revision, newV.Revision)), nil
+}
+
+func (d debianComparer) IsPrerelease(newVersion string) (bool, error) {
+	v, err := d.parse(newVersion)
+	if err != nil {
+		return false, fmt.Errorf("parsing version: %w", err)
+	}
+
+	// Debian marks pre-releases with a tilde (1.0~rc1) sorting them
+	// before the final release
+	return strings.Contains(v.Upstream, "~"), nil
+}
+
+func (debianComparer) parse(ver string) (v debianVersion, err error) {
+	if epoch, rest, found := strings.Cut(ver, ":"); found {
+		if v.Epoch, err = strconv.Atoi(epoch); err != nil || v.Epoch < 0 {
+			return v, fmt.Errorf("invalid epoch %q", epoch)
+		}
+		ver = rest
+	}
+
+	v.Upstream = ver
+	if idx := strings.LastIndex(ver, "-"); idx >= 0 {
+		v.Upstream, v.Revision = ver[:idx], ver[idx+1:]
+
+		if !debianRevisionRegex.MatchString(v.Revision) {
+			return v, fmt.Errorf("invalid revision %q", v.Revision)
+		}
+	}
+
+	if v.Upstream == "" {
+		return v, errors.New("empty upstream version")
+	}
+
+	if !debianUpstreamRegex.MatchString(v.Upstream) {
+		return v, fmt.Errorf("invalid upstream version %q", v.Upstream)
+	}
+
+	return v, nil
+}
+
+// debianVerRevCmp implements the comparison of upstream version and
+// revision as done in dpkg: non-digit parts are compared with letters
+// sorting before non-letters and tilde sorting before everything,
+// digit parts are compared numerically
+func debianVerRevCmp(a, b string) int {
+	for a != "" || b != "" {
+		for (a != "" && !isASCIIDigit(a[0])) || (b != "" && !isASCIIDigit(b[0])) {
+			if ac, bc := debianCharOrder(a), debianCharOrder(b); ac != bc {
+				return ac - bc
+			}
+			a, b = dropFirst(a), dropFirst(b)
+		}
+
+		a, b = strings.TrimLeft(a, "0"), strings.TrimLeft(b, "0")
+
+		firstDiff := 0
+		for a != "" && b != "" && isASCIIDigit(a[0]) && isASCIIDigit(b[0]) {
+			if firstDiff == 0 {
+				firstDiff = int(a[0]) - int(b[0])
+			}
+			a, b = a[1:], b[1:]
+		}
+
+		switch {
+		case a != "" && isASCIIDigit(a[0]):
+			return 1
+		case b != "" && isASCIIDigit(b[0]):
+			return -1
+		case firstDiff != 0:
+			return firstDiff
+		}
+	}
+
+	return 0
+}
+
+func debianCharOrder(s string) int {
+	switch {
+	case s == "", isASCIIDigit(s[0]):
+		return 0
+	case isASCIILetter(s[0]):
+		return int(s[0])
+	case s[0] == '~':
+		return -1
+	default:
+		return int(s[0]) + 256 //revive:disable-line:add-constant // Moves non-letters behind letters as in dpkg
+	}
+}
+
+func dropFirst(s string) string {
+	if s == "" {
+		return s
+	}
+	return s[1:]
+}
+
+func isASCIIDigit(c byte) bool { return c >= '0' && c <= '9' }
+
+func isASCIILetter(c byte) bool { return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') }
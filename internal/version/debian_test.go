@@ -0,0 +1,32 @@
+package version
+
+import "testing"
+
+func TestDebianCompareFunc(t *testing.T) {
+	comp := debianComparer{}
+
+	for _, tc := range []struct {
+		v1, v2 string
+		res    compareResult
+	}{
+		{"1.0", "1.1", compareResultUpgrade},
+		{"1.0-1", "1.0-2", compareResultUpgrade},
+		{"1.0~rc1-1", "1.0-1", compareResultUpgrade},
+		{"1.0-1", "1.0~rc1-1", compareResultDowngrade},
+		{"1:1.0-1", "2.0-1", compareResultDowngrade},
+		{"2.34-0ubuntu3", "2.34-0ubuntu3.2", compareResultUpgrade},
+		{"1.2.10", "1.2.9", compareResultDowngrade},
+		{"1.0a", "1.0+b", compareResultUpgrade},
+		{"1:2.3.4-5", "1:2.3.4-5", compareResultEqual},
+		{"7.6p1-4", "7.6p2-1", compareResultUpgrade},
+	} {
+		res, err := comp.Compare(tc.v1, tc.v2)
+		if err != nil {
+			t.Errorf("Comparing %q to %q: %s", tc.v1, tc.v2, err)
+		}
+
+		if res != tc.res {
+			t.Errorf("Comparing %q to %q: expected %v, got %v", tc.v1, tc.v2, tc.res, res)
+		}
+	}
+}
@@ -0,0 +1,293 @@
+package version
+
+import (
+	"cmp"
+	"fmt"
+	"math/big"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+type (
+	mavenComparer struct{}
+
+	// mavenItem is one of mavenIntItem, mavenStringItem or
+	// mavenListItem, a nil mavenItem represents a missing item
+	mavenItem interface {
+		compare(other mavenItem) int
+		isNull() bool
+	}
+
+	mavenIntItem    struct{ value *big.Int }
+	mavenStringItem struct{ value string }
+	mavenListItem   []mavenItem
+)
+
+var (
+	_ comparer = mavenComparer{}
+
+	mavenQualifiers       = []string{"alpha", "beta", "milestone", "rc", "snapshot", "", "sp"}
+	mavenQualifierAliases = map[string]string{"ga": "", "final": "", "release": "", "cr": "rc"}
+	mavenReleaseIndex     = mavenComparableQualifier("")
+)
+
+func (m mavenComparer) Compare(oldVersion, newVersion string) (compareResult, error) {
+	oldV, err := m.parse(oldVersion)
+	if err != nil {
+		return compareResultInvalid, fmt.Errorf("parsing old version: %w", err)
+	}
+
+	newV, err := m.parse(newVersion)
+	if err != nil {
+		return compareResultInvalid, fmt.Errorf("parsing new version: %w", err)
+	}
+
+	return compareResultFromCmp(oldV.compare(newV)), nil
+}
+
+func (m mavenComparer) IsPrerelease(newVersion string) (bool, error) {
+	v, err := m.parse(newVersion)
+	if err != nil {
+		return false, fmt.Errorf("parsing version: %w", err)
+	}
+
+	return v.hasPrereleaseQualifier(), nil
+}
+
+// parse implements the parsing of Maven ComparableVersion: the
+// version is split at dots, dashes and transitions between digits and
+// letters, dashes and transitions start a new sub-list
+func (mavenComparer) parse(ver string) (mavenListItem, error) {
+	ver = strings.ToLower(ver)
+	if ver == "" || !isASCIIDigit(ver[0]) {
+		return nil, fmt.Errorf("version %q does not start with a digit", ver)
+	}
+
+	var (
+		root  = &mavenListItem{}
+		list  = root
+		stack = []*mavenListItem{root}
+
+		isDigit bool
+		start   int
+	)
+
+	newSubList := func() {
+		sub := &mavenListItem{}
+		*list = append(*list, sub)
+		list = sub
+		stack = append(stack, sub)
+	}
+
+	for i := 0; i < len(ver); i++ {
+		c := ver[i]
+
+		switch {
+		case c == '.' || c == '-':
+			if i == start {
+				*list = append(*list, mavenIntItem{big.NewInt(0)})
+			} else {
+				*list = append(*list, mavenParseItem(isDigit, ver[start:i], ver[i:]))
+			}
+			start = i + 1
+
+			if c == '-' {
+				newSubList()
+			}
+
+		case isASCIIDigit(c):
+			if !isDigit && i > start {
+				*list = append(*list, mavenParseItem(false, ver[start:i], ver[i:]))
+				start = i
+				newSubList()
+			}
+			isDigit = true
+
+		default:
+			if isDigit && i > start {
+				*list = append(*list, mavenParseItem(true, ver[start:i], ver[i:]))
+				start = i
+				newSubList()
+			}
+			isDigit = false
+		}
+	}
+
+	if len(ver) > start {
+		if !isDigit && len(*list) > 0 {
+			// Trailing qualifiers are treated the same no matter whether
+			// separated by dot or dash: 1.0.0.X1 < 1.0.0-X2
+			newSubList()
+		}
+		*list = append(*list, mavenParseItem(isDigit, ver[start:], ""))
+	}
+
+	for _, l := range slices.Backward(stack) {
+		l.normalize()
+	}
+
+	return root.deref(), nil
+}
+
+func mavenComparableQualifier(q string) string {
+	if idx := slices.Index(mavenQualifiers, q); idx >= 0 {
+		return strconv.Itoa(idx)
+	}
+
+	// Unknown qualifiers are sorted lexically after all known ones
+	return fmt.Sprintf("%d-%s", len(mavenQualifiers), q)
+}
+
+func mavenParseItem(isDigit bool, s, following string) mavenItem {
+	if isDigit {
+		v, _ := new(big.Int).SetString(s, 10) //revive:disable-line:add-constant // Decimal base, parser ensures digits
+		return mavenIntItem{v}
+	}
+
+	if len(s) == 1 && following != "" && isASCIIDigit(following[0]) {
+		// Single letters followed by a digit are short forms
+		switch s {
+		case "a":
+			s = "alpha"
+		case "b":
+			s = "beta"
+		case "m":
+			s = "milestone"
+		}
+	}
+
+	if alias, ok := mavenQualifierAliases[s]; ok {
+		s = alias
+	}
+
+	return mavenStringItem{s}
+}
+
+func (i mavenIntItem) compare(other mavenItem) int {
+	switch o := other.(type) {
+	case nil:
+		return i.value.Sign()
+	case mavenIntItem:
+		return i.value.Cmp(o.value)
+	default:
+		// Numbers are newer than qualifiers and sub-lists
+		return 1
+	}
+}
+
+func (i mavenIntItem) isNull() bool { return i.value.Sign() == 0 }
+
+func (l mavenListItem) compare(other mavenItem) int {
+	switch o := other.(type) {
+	case nil:
+		if len(l) == 0 {
+			return 0
+		}
+		return l[0].compare(nil)
+
+	case mavenIntItem:
+		return -1
+
+	case mavenStringItem:
+		return 1
+
+	case mavenListItem:
+		for idx := range max(len(l), len(o)) {
+			var left, right mavenItem
+			if idx < len(l) {
+				left = l[idx]
+			}
+			if idx < len(o) {
+				right = o[idx]
+			}
+
+			var res int
+			if left == nil {
+				if right != nil {
+					res = -right.compare(nil)
+				}
+			} else {
+				res = left.compare(right)
+			}
+
+			if res != 0 {
+				return res
+			}
+		}
+		return 0
+
+	default:
+		return 0
+	}
+}
+
+// deref converts nested list pointers used while parsing into values
+func (l *mavenListItem) deref() mavenListItem {
+	out := make(mavenListItem, 0, len(*l))
+	for _, item := range *l {
+		if sub, ok := item.(*mavenListItem); ok {
+			out = append(out, sub.deref())
+			continue
+		}
+		out = append(out, item)
+	}
+
+	return out
+}
+
+func (l mavenListItem) hasPrereleaseQualifier() bool {
+	for _, item := range l {
+		switch it := item.(type) {
+		case mavenStringItem:
+			if mavenComparableQualifier(it.value) < mavenReleaseIndex {
+				return true
+			}
+
+		case mavenListItem:
+			if it.hasPrereleaseQualifier() {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func (l mavenListItem) isNull() bool { return len(l) == 0 }
+
+// normalize removes trailing null items (zeros, release qualifiers
+// and empty lists) until reaching a non-list item
+func (l *mavenListItem) normalize() {
+	for i := len(*l) - 1; i >= 0; i-- {
+		item := (*l)[i]
+
+		if sub, ok := item.(*mavenListItem); ok {
+			if len(*sub) == 0 {
+				*l = slices.Delete(*l, i, i+1)
+			}
+			continue
+		}
+
+		if item.isNull() {
+			*l = slices.Delete(*l, i, i+1)
+			continue
+		}
+
+		break
+	}
+}
+
+func (s mavenStringItem) compare(other mavenItem) int {
+	switch o := other.(type) {
+	case nil:
+		return cmp.Compare(mavenComparableQualifier(s.value), mavenReleaseIndex)
+	case mavenStringItem:
+		return cmp.Compare(mavenComparableQualifier(s.value), mavenComparableQualifier(o.value))
+	default:
+		// Qualifiers are older than numbers and sub-lists
+		return -1
+	}
+}
+
+func (s mavenStringItem) isNull() bool { return mavenComparableQualifier(s.value) == mavenReleaseIndex }
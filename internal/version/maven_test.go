@@ -0,0 +1,36 @@
+package version
+
+import "testing"
+
+func TestMavenCompareFunc(t *testing.T) {
+	comp := mavenComparer{}
+
+	for _, tc := range []struct {
+		v1, v2 string
+		res    compareResult
+	}{
+		{"1.0", "1.1", compareResultUpgrade},
+		{"1.0-alpha-1", "1.0-beta-1", compareResultUpgrade},
+		{"1.0-beta-1", "1.0-rc-1", compareResultUpgrade},
+		{"1.0-rc-1", "1.0-SNAPSHOT", compareResultUpgrade},
+		{"1.0-SNAPSHOT", "1.0", compareResultUpgrade},
+		{"1.0", "1.0-sp-1", compareResultUpgrade},
+		{"1.0", "1.0.0.Final", compareResultEqual},
+		{"1.0-ga", "1.0", compareResultEqual},
+		{"1.0-cr1", "1.0-rc1", compareResultEqual},
+		{"1.0a1", "1.0-alpha-1", compareResultEqual},
+		{"2.0", "1.10", compareResultDowngrade},
+		{"1.0.1", "1.0-1", compareResultDowngrade},
+		{"32.1.3-jre", "33.0.0-jre", compareResultUpgrade},
+		{"5.3.9", "5.3.10", compareResultUpgrade},
+	} {
+		res, err := comp.Compare(tc.v1, tc.v2)
+		if err != nil {
+			t.Errorf("Comparing %q to %q: %s", tc.v1, tc.v2, err)
+		}
+
+		if res != tc.res {
+			t.Errorf("Comparing %q to %q: expected %v, got %v", tc.v1, tc.v2, tc.res, res)
+		}
+	}
+}
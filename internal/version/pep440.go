@@ -0,0 +1,169 @@
+package version
+
+import (
+	"cmp"
+	"fmt"
+	"math"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+type (
+	pep440Comparer struct{}
+
+	pep440Version struct {
+		Epoch   int
+		Release []int
+
+		// Pre-, post- and dev-release numbers are converted into keys
+		// sortable according to PEP 440: a missing pre-release of a
+		// dev-release sorts before all pre-releases, a missing
+		// pre-release otherwise sorts after all pre-releases, ...
+		Pre   [2]int
+		Post  int
+		Dev   int
+		Local []string
+
+		isPre bool
+		isDev bool
+	}
+)
+
+var (
+	_ comparer = pep440Comparer{}
+
+	pep440Regex = regexp.MustCompile(`(?i)^v?` +
+		`(?:(?P<epoch>[0-9]+)!)?` +
+		`(?P<release>[0-9]+(?:\.[0-9]+)*)` +
+		`(?P<pre>[-_.]?(?P<pre_l>alpha|a|beta|b|preview|pre|c|rc)[-_.]?(?P<pre_n>[0-9]+)?)?` +
+		`(?P<post>-(?P<post_n1>[0-9]+)|[-_.]?(?P<post_l>post|rev|r)[-_.]?(?P<post_n2>[0-9]+)?)?` +
+		`(?P<dev>[-_.]?dev[-_.]?(?P<dev_n>[0-9]+)?)?` +
+		`(?:\+(?P<local>[a-z0-9]+(?:[-_.][a-z0-9]+)*))?$`)
+
+	pep440PreLabels = map[string]int{
+		"a": 0, "alpha": 0,
+		"b": 1, "beta": 1,
+		"c": 2, "pre": 2, "preview": 2, "rc": 2,
+	}
+)
+
+func (p pep440Comparer) Compare(oldVersion, newVersion string) (compareResult, error) {
+	oldV, err := p.parse(oldVersion)
+	if err != nil {
+		return compareResultInvalid, fmt.Errorf("parsing old version: %w", err)
+	}
+
+	newV, err := p.parse(newVersion)
+	if err != nil {
+		return compareResultInvalid, fmt.Errorf("parsing new version: %w", err)
+	}
+
+	return compareResultFromCmp(oldV.compare(newV)), nil
+}
+
+func (p pep440Comparer) IsPrerelease(newVersion string) (bool, error) {
+	v, err := p.parse(newVersion)
+	if err != nil {
+		return false, fmt.Errorf("parsing version: %w", err)
+	}
+
+	return v.isPre || v.isDev, nil
+}
+
+func (pep440Comparer) parse(ver string) (pep440Version, error) {
+	var (
+		v     pep440Version
+		match = pep440Regex.FindStringSubmatch(strings.TrimSpace(ver))
+	)
+
+	if match == nil {
+		return v, fmt.Errorf("version %q is not valid according to PEP 440", ver)
+	}
+
+	group := func(name string) string { return match[pep440Regex.SubexpIndex(name)] }
+	atoi := func(s string) int {
+		n, _ := strconv.Atoi(s) // Regex ensures digits, empty is zero
+		return n
+	}
+
+	v.Epoch = atoi(group("epoch"))
+
+	for seg := range strings.SplitSeq(group("release"), ".") {
+		v.Release = append(v.Release, atoi(seg))
+	}
+	for len(v.Release) > 1 && v.Release[len(v.Release)-1] == 0 {
+		// Trailing zeros are not significant: 1.0 == 1.0.0
+		v.Release = v.Release[:len(v.Release)-1]
+	}
+
+	v.isPre = group("pre") != ""
+	v.isDev = group("dev") != ""
+	isPost := group("post") != ""
+
+	switch {
+	case v.isPre:
+		v.Pre = [2]int{pep440PreLabels[strings.ToLower(group("pre_l"))], atoi(group("pre_n"))}
+	case !isPost && v.isDev:
+		// Dev-release of a final version sorts before its pre-releases
+		v.Pre = [2]int{math.MinInt, 0}
+	default:
+		v.Pre = [2]int{math.MaxInt, 0}
+	}
+
+	v.Post = math.MinInt
+	if isPost {
+		v.Post = atoi(group("post_n1") + group("post_n2"))
+	}
+
+	v.Dev = math.MaxInt
+	if v.isDev {
+		v.Dev = atoi(group("dev_n"))
+	}
+
+	if local := group("local"); local != "" {
+		v.Local = strings.FieldsFunc(strings.ToLower(local), func(r rune) bool { return r == '-' || r == '_' || r == '.' })
+	}
+
+	return v, nil
+}
+
+func (v pep440Version) compare(o pep440Version) int {
+	if c := cmp.Compare(v.Epoch, o.Epoch); c != 0 {
+		return c
+	}
+
+	if c := slices.Compare(v.Release, o.Release); c != 0 {
+		return c
+	}
+
+	if c := slices.Compare(v.Pre[:], o.Pre[:]); c != 0 {
+		return c
+	}
+
+	if c := cmp.Compare(v.Post, o.Post); c != 0 {
+		return c
+	}
+
+	if c := cmp.Compare(v.Dev, o.Dev); c != 0 {
+		return c
+	}
+
+	return slices.CompareFunc(v.Local, o.Local, func(a, b string) int {
+		// Numeric local segments sort after alphanumeric ones
+		aN, aErr := strconv.Atoi(a)
+		bN, bErr := strconv.Atoi(b)
+
+		switch {
+		case aErr == nil && bErr == nil:
+			return cmp.Compare(aN, bN)
+		case aErr == nil:
+			return 1
+		case bErr == nil:
+			return -1
+		default:
+			return strings.Compare(a, b)
+		}
+	})
+}
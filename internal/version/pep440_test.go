@@ -0,0 +1,36 @@
+package version
+
+import "testing"
+
+func TestPEP440CompareFunc(t *testing.T) {
+	comp := pep440Comparer{}
+
+	for _, tc := range []struct {
+		v1, v2 string
+		res    compareResult
+	}{
+		{"1.0", "1.0.1", compareResultUpgrade},
+		{"1.0.dev0", "1.0a1", compareResultUpgrade},
+		{"1.0a1", "1.0a2", compareResultUpgrade},
+		{"1.0a2", "1.0b1", compareResultUpgrade},
+		{"1.0b1", "1.0rc1", compareResultUpgrade},
+		{"1.0rc1", "1.0", compareResultUpgrade},
+		{"1.0", "1.0.post1", compareResultUpgrade},
+		{"1.0.post1.dev1", "1.0.post1", compareResultUpgrade},
+		{"1.0", "1.0+local.1", compareResultUpgrade},
+		{"1!0.1", "2.0", compareResultDowngrade},
+		{"1.0", "1.0.0", compareResultEqual},
+		{"1.0-1", "1.0.post1", compareResultEqual},
+		{"1.0.0-Alpha.1", "1.0.0a1", compareResultEqual},
+		{"v24.0", "24.1", compareResultUpgrade},
+	} {
+		res, err := comp.Compare(tc.v1, tc.v2)
+		if err != nil {
+			t.Errorf("Comparing %q to %q: %s", tc.v1, tc.v2, err)
+		}
+
+		if res != tc.res {
+			t.Errorf("Comparing %q to %q: expected %v, got %v", tc.v1, tc.v2, tc.res, res)
+		}
+	}
+}
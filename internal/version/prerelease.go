@@ -0,0 +1,124 @@
+package version
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+const (
+	prereleaseRankDev = iota
+	prereleaseRankAlpha
+	prereleaseRankBeta
+	prereleaseRankRC
+	prereleaseRankFinal
+	prereleaseRankPost
+)
+
+var prereleaseMarkers = map[string]int{
+	"dev":      prereleaseRankDev,
+	"canary":   prereleaseRankDev,
+	"nightly":  prereleaseRankDev,
+	"snapshot": prereleaseRankDev,
+
+	"a":     prereleaseRankAlpha,
+	"alpha": prereleaseRankAlpha,
+	"ea":    prereleaseRankAlpha,
+
+	"b":         prereleaseRankBeta,
+	"beta":      prereleaseRankBeta,
+	"m":         prereleaseRankBeta,
+	"milestone": prereleaseRankBeta,
+
+	"c":       prereleaseRankRC,
+	"cr":      prereleaseRankRC,
+	"pre":     prereleaseRankRC,
+	"preview": prereleaseRankRC,
+	"rc":      prereleaseRankRC,
+}
+
+// hasPrereleaseMarker checks whether any of the letter-runs inside
+// the version is a known pre-release marker. Single letters are not
+// considered as they are commonly used for patch levels (1.1.1c).
+func hasPrereleaseMarker(ver string) bool {
+	for _, run := range splitVersionRuns(strings.ToLower(ver)) {
+		if len(run) < 2 || !unicode.IsLetter(rune(run[0])) { //revive:disable-line:add-constant // Length of a single letter
+			continue
+		}
+
+		if rank, ok := prereleaseMarkers[run]; ok && rank < prereleaseRankFinal {
+			return true
+		}
+	}
+
+	return false
+}
+
+// parseModifier rates a version modifier like "rc1", "beta.2" or
+// "dev0" by its first letter-run and first number. Empty modifiers are
+// final releases, unknown modifiers sort after final releases.
+func parseModifier(mod string) (rank, num int) {
+	rank = prereleaseRankFinal
+	if mod == "" {
+		return rank, 0
+	}
+
+	rank = prereleaseRankPost
+	var letterSeen, numberSeen bool
+	for _, run := range splitVersionRuns(strings.ToLower(mod)) {
+		switch {
+		case unicode.IsLetter(rune(run[0])) && !letterSeen:
+			letterSeen = true
+			if r, ok := prereleaseMarkers[run]; ok {
+				rank = r
+			}
+
+		case unicode.IsDigit(rune(run[0])) && !numberSeen:
+			numberSeen = true
+			num, _ = strconv.Atoi(run)
+		}
+	}
+
+	return rank, num
+}
+
+// splitVersionRuns splits the version into runs of letters and runs of
+// digits, dropping all other characters
+func splitVersionRuns(ver string) []string {
+	var (
+		runs    []string
+		current strings.Builder
+		isDigit bool
+	)
+
+	flush := func() {
+		if current.Len() > 0 {
+			runs = append(runs, current.String())
+			current.Reset()
+		}
+	}
+
+	for _, c := range ver {
+		switch {
+		case unicode.IsDigit(c):
+			if !isDigit {
+				flush()
+			}
+			isDigit = true
+			current.WriteRune(c)
+
+		case unicode.IsLetter(c):
+			if isDigit {
+				flush()
+			}
+			isDigit = false
+			current.WriteRune(c)
+
+		default:
+			flush()
+		}
+	}
+	flush()
+
+	return runs
+}
@@ -0,0 +1,54 @@
+package version
+
+import "testing"
+
+func TestIsPrerelease(t *testing.T) {
+	for _, tc := range []struct {
+		typ, ver string
+		pre      bool
+	}{
+		{"calver", "2024.1.0", false},
+		{"calver", "2024.1.0rc1", true},
+		{"calver", "24.04-beta", true},
+		{"debian", "1.0-1", false},
+		{"debian", "1.0~rc1-1", true},
+		{"maven", "1.0", false},
+		{"maven", "1.0-SNAPSHOT", true},
+		{"maven", "1.0-M1", true},
+		{"maven", "1.0.Final", false},
+		{"maven", "1.0-sp1", false},
+		{"pep440", "1.0", false},
+		{"pep440", "1.0.post1", false},
+		{"pep440", "1.0a1", true},
+		{"pep440", "1.0.dev0", true},
+		{"rpm", "1.1.1k-7.el8", false},
+		{"rpm", "1.0~rc1-1", true},
+		{"rpm", "1.0-0.1.beta2.fc39", true},
+	} {
+		pre, err := Constraint{Type: tc.typ}.getComparer().IsPrerelease(tc.ver)
+		if err != nil {
+			t.Errorf("checking %s version %q: %s", tc.typ, tc.ver, err)
+			continue
+		}
+
+		if pre != tc.pre {
+			t.Errorf("checking %s version %q: expected %v, got %v", tc.typ, tc.ver, tc.pre, pre)
+		}
+	}
+}
+
+func TestInvalidVersions(t *testing.T) {
+	for _, tc := range []struct{ typ, ver string }{
+		{"calver", "latest"},
+		{"calver", "1.2.3"},
+		{"debian", "abc"},
+		{"debian", "x:1.0"},
+		{"maven", "latest"},
+		{"pep440", "1.0-foo"},
+		{"rpm", "v1.0"},
+	} {
+		if _, err := (Constraint{Type: tc.typ}.getComparer()).Compare(tc.ver, tc.ver); err == nil {
+			t.Errorf("parsing invalid %s version %q did not cause error", tc.typ, tc.ver)
+		}
+	}
+}
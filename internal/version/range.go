@@ -0,0 +1,292 @@
+package version
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// semVerRangeSegments is the number of segments numeric range bounds
+// are padded to for types requiring all three semver segments
+const semVerRangeSegments = 3
+
+type (
+	// versionRange is a set of clause-groups of which at least one
+	// group must be satisfied completely
+	versionRange [][]rangeClause
+
+	rangeClause struct {
+		op      string
+		version string
+		// excludePrereleases marks upper bounds generated from "~", "^"
+		// and ".x" which must not match pre-releases of the bound itself
+		// ("~1.28" must not match "1.29.0-rc.1")
+		excludePrereleases bool
+	}
+)
+
+var (
+	rangeOperators = []string{">=", "<=", "==", "!=", "~=", ">", "<", "=", "~", "^"}
+
+	// rangeReleasePrefix matches the numeric release part of a version
+	// in front of any pre-release marker
+	rangeReleasePrefix = regexp.MustCompile(`^v?([0-9]+(?:\.[0-9]+)*)`)
+)
+
+// parseRange parses expressions like ">=1.28.0 <1.29.0", "~3.11",
+// "^2", "~=2.2" or "1.28.x" where whitespace or comma separated clauses
+// must all match and "||" separates alternatives. Numeric bounds are
+// padded with zeros to minSegments segments.
+func parseRange(expr string, minSegments int) (versionRange, error) {
+	var out versionRange
+
+	for group := range strings.SplitSeq(expr, "||") {
+		clauses, err := parseRangeGroup(group, minSegments)
+		if err != nil {
+			return nil, err
+		}
+
+		out = append(out, clauses)
+	}
+
+	return out, nil
+}
+
+func parseRangeGroup(group string, minSegments int) ([]rangeClause, error) {
+	var (
+		clauses []rangeClause
+		pending string
+	)
+
+	for token := range strings.FieldsFuncSeq(group, func(r rune) bool { return r == ' ' || r == ',' || r == '\t' }) {
+		token = pending + token
+		pending = ""
+
+		op := ""
+		for _, o := range rangeOperators {
+			if strings.HasPrefix(token, o) {
+				op = o
+				break
+			}
+		}
+
+		if token == op {
+			// Operator separated from version by whitespace
+			pending = op
+			continue
+		}
+
+		expanded, err := expandRangeClause(op, strings.TrimPrefix(token, op), minSegments)
+		if err != nil {
+			return nil, fmt.Errorf("parsing clause %q: %w", token, err)
+		}
+
+		clauses = append(clauses, expanded...)
+	}
+
+	if pending != "" {
+		return nil, fmt.Errorf("operator %q without version", pending)
+	}
+
+	if len(clauses) == 0 {
+		return nil, errors.New("empty range group")
+	}
+
+	return clauses, nil
+}
+
+func expandRangeClause(op, ver string, minSegments int) ([]rangeClause, error) {
+	if ver == "" || strings.ContainsAny(ver[:1], "<>=!~^") {
+		return nil, errors.New("missing version")
+	}
+
+	if op == "" || op == "=" || op == "==" {
+		if base, ok := strings.CutSuffix(ver, ".x"); ok {
+			return expandRangeClause("~", base, minSegments)
+		}
+		if base, ok := strings.CutSuffix(ver, ".*"); ok {
+			return expandRangeClause("~", base, minSegments)
+		}
+	}
+
+	switch op {
+	case "~", "^", "~=":
+		segs, err := parseRangeSegments(ver)
+		if err != nil {
+			return nil, err
+		}
+
+		if op == "~=" && len(segs) < 2 { //nolint:mnd // Compatible release needs two segments
+			return nil, errors.New("compatible release requires at least two segments")
+		}
+
+		return []rangeClause{
+			{op: ">=", version: joinRangeSegments(segs, minSegments)},
+			{op: "<", version: joinRangeSegments(bumpRangeSegments(op, segs), minSegments), excludePrereleases: true},
+		}, nil
+
+	case "", "==":
+		op = "="
+	}
+
+	if segs, err := parseRangeSegments(ver); err == nil {
+		// Purely numeric versions are padded to be parseable as semver
+		ver = joinRangeSegments(segs, minSegments)
+	}
+
+	return []rangeClause{{op: op, version: ver}}, nil
+}
+
+func bumpRangeSegments(op string, segs []int) []int {
+	// Tilde allows changes below the minor version if specified,
+	// caret allows changes below the first non-zero segment and the
+	// compatible release allows changes of the last segment
+	pos := 0
+	switch op {
+	case "~":
+		pos = min(1, len(segs)-1)
+
+	case "~=":
+		pos = len(segs) - 2 //nolint:mnd // Second to last segment
+
+	case "^":
+		for pos < len(segs)-1 && segs[pos] == 0 {
+			pos++
+		}
+	}
+
+	upper := slices.Clone(segs[:pos+1])
+	upper[pos]++
+	return upper
+}
+
+func joinRangeSegments(segs []int, minSegments int) string {
+	parts := make([]string, 0, max(len(segs), minSegments))
+	for _, s := range segs {
+		parts = append(parts, strconv.Itoa(s))
+	}
+	for len(parts) < minSegments {
+		parts = append(parts, "0")
+	}
+
+	return strings.Join(parts, ".")
+}
+
+func parseRangeSegments(ver string) ([]int, error) {
+	var segs []int
+
+	for seg := range strings.SplitSeq(ver, ".") {
+		segI, err := strconv.Atoi(seg)
+		if err != nil || segI < 0 {
+			return nil, fmt.Errorf("invalid numeric segment %q", seg)
+		}
+		segs = append(segs, segI)
+	}
+
+	return segs, nil
+}
+
+// rangeGroupSatisfiable checks whether any version can satisfy all
+// lower and upper bounds of the group
+func rangeGroupSatisfiable(comp comparer, group []rangeClause) bool {
+	for _, lower := range group {
+		if lower.op != ">=" && lower.op != ">" && lower.op != "=" {
+			continue
+		}
+
+		for _, upper := range group {
+			if upper.op != "<=" && upper.op != "<" && upper.op != "=" {
+				continue
+			}
+
+			res, err := comp.Compare(lower.version, upper.version)
+			switch {
+			case err != nil:
+				// Invalid versions are reported separately
+				continue
+
+			case res == compareResultDowngrade:
+				// Lower bound is above the upper bound
+				return false
+
+			case res == compareResultEqual && (lower.op == ">" || upper.op == "<"):
+				// Bounds exclude the only version in between
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+func (r versionRange) contains(comp comparer, ver string) (bool, error) {
+	for _, group := range r {
+		matches := true
+
+		for _, clause := range group {
+			ok, err := clause.matches(comp, ver)
+			if err != nil {
+				return false, err
+			}
+
+			if !ok {
+				matches = false
+				break
+			}
+		}
+
+		if matches {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+func (r rangeClause) matches(comp comparer, ver string) (bool, error) {
+	// The result describes the step from the clause version to the
+	// checked version: an upgrade means the version is greater
+	res, err := comp.Compare(r.version, ver)
+	if err != nil {
+		return false, fmt.Errorf("comparing to %q: %w", r.version, err)
+	}
+
+	switch r.op {
+	case ">=":
+		return res == compareResultUpgrade || res == compareResultEqual, nil
+	case ">":
+		return res == compareResultUpgrade, nil
+	case "<=":
+		return res == compareResultDowngrade || res == compareResultEqual, nil
+	case "<":
+		if res == compareResultDowngrade && r.excludePrereleases {
+			return !r.isPrereleaseOfBound(comp, ver), nil
+		}
+		return res == compareResultDowngrade, nil
+	case "=":
+		return res == compareResultEqual, nil
+	case "!=":
+		return res != compareResultEqual, nil
+	default:
+		return false, fmt.Errorf("invalid operator %q", r.op)
+	}
+}
+
+// isPrereleaseOfBound checks whether the version is a pre-release of
+// the clause version (for example "1.29.0-rc.1" of "1.29.0")
+func (r rangeClause) isPrereleaseOfBound(comp comparer, ver string) bool {
+	if isPreR, err := comp.IsPrerelease(ver); err != nil || !isPreR {
+		return false
+	}
+
+	m := rangeReleasePrefix.FindStringSubmatch(ver)
+	if m == nil {
+		return false
+	}
+
+	res, err := comp.Compare(r.version, m[1])
+	return err == nil && res == compareResultEqual
+}
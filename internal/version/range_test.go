@@ -0,0 +1,141 @@
+package version
+
+import "testing"
+
+func TestRangeContains(t *testing.T) {
+	for _, tc := range []struct {
+		expr, ver string
+		typ       string
+		contained bool
+	}{
+		{">=1.28.0 <1.29.0", "1.28.4", "semver", true},
+		{">=1.28.0 <1.29.0", "1.29.0", "semver", false},
+		{">=1.28.0, <1.29.0", "1.27.9", "semver", false},
+		{">= 1.28 < 1.29", "1.28.1", "semver", true},
+		{"~3.11", "3.11.7", "semver", true},
+		{"~3.11", "3.12.0", "semver", false},
+		{"~1.2.3", "1.2.9", "semver", true},
+		{"~1.2.3", "1.2.2", "semver", false},
+		{"^2", "2.9.1", "semver", true},
+		{"^2", "3.0.0", "semver", false},
+		{"^0.2.3", "0.2.9", "semver", true},
+		{"^0.2.3", "0.3.0", "semver", false},
+		{"1.28.x", "1.28.12", "semver", true},
+		{"1.28.x", "1.30.0", "semver", false},
+		{"~15", "15.6", "numeric_dot", true},
+		{"~15", "16.1", "numeric_dot", false},
+		{"^1 || ^3", "3.1.0", "semver", true},
+		{"^1 || ^3", "2.1.0", "semver", false},
+		{"!=1.2.0", "1.2.0", "semver", false},
+		{"1.2.0", "1.2.0", "semver", true},
+		{"~1.28", "1.29.0-rc.1", "semver", false},
+		{"~1.28", "1.28.5-rc.1", "semver", true},
+		{"^1", "2.0.0-beta.2", "semver", false},
+		{"1.28.x", "1.29.0-alpha", "semver", false},
+		{"<1.29.0", "1.29.0-rc.1", "semver", true},
+		{"~3.11", "3.12.0a1", "pep440", false},
+		{"~3.11", "3.12a1", "pep440", false},
+		{"~3.11", "3.11.5rc1", "pep440", true},
+		{"~=2.2", "2.9", "pep440", true},
+		{"~=2.2", "3.0", "pep440", false},
+		{"~=1.4.5", "1.4.9", "pep440", true},
+		{"~=1.4.5", "1.4.4", "pep440", false},
+		{"~=1.4.5", "1.5.0", "pep440", false},
+		{"~=1.4.5", "1.5.0rc1", "pep440", false},
+		{"~=1.4.5", "1.4.7", "semver", true},
+		{">=1.2", "1.2-3ubuntu1", "debian", true},
+		{">=1.2", "1.2~rc1-1", "debian", false},
+		{"~1.2", "1.2-1", "debian", true},
+		{"~1.2", "1.2.5-2", "debian", true},
+		{"~1.2", "1.3-1", "debian", false},
+		{"~1.2", "1.3~rc1-1", "debian", false},
+		{"1.2.x", "1.2-1", "debian", true},
+		{">=1.2", "1.2-3.el8", "rpm", true},
+		{"~1.2", "1.2-1.el8", "rpm", true},
+		{"~1.2", "1.2.3-1.el8", "rpm", true},
+		{"~1.2", "1.3-1.el8", "rpm", false},
+		{"~1.2", "1.3~rc1-1.el8", "rpm", false},
+	} {
+		c := Constraint{Type: tc.typ}
+
+		r, err := parseRange(tc.expr, c.rangeSegments())
+		if err != nil {
+			t.Errorf("parsing range %q: %s", tc.expr, err)
+			continue
+		}
+
+		contained, err := r.contains(c.getComparer(), tc.ver)
+		if err != nil {
+			t.Errorf("checking %q in %q: %s", tc.ver, tc.expr, err)
+			continue
+		}
+
+		if contained != tc.contained {
+			t.Errorf("checking %q in %q: expected %v, got %v", tc.ver, tc.expr, tc.contained, contained)
+		}
+	}
+}
+
+func TestRangeParseInvalid(t *testing.T) {
+	for _, expr := range []string{"", ">=", "~a.b", "^1.x", "1.0 ||", "~=1", "~=1.x"} {
+		if _, err := parseRange(expr, semVerRangeSegments); err == nil {
+			t.Errorf("parsing invalid range %q did not cause error", expr)
+		}
+	}
+}
+
+func TestConstraintRange(t *testing.T) {
+	c := Constraint{Type: "semver", Range: "~1.28"}
+
+	for _, tc := range []struct {
+		old, new string
+		apply    bool
+	}{
+		{"", "1.29.0", false},
+		{"", "1.28.3", true},
+		{"1.28.3", "1.28.4", true},
+		{"1.28.4", "1.29.0", false},
+	} {
+		apply, err := c.ShouldApply(tc.old, tc.new)
+		if err != nil {
+			t.Errorf("checking %q -> %q: %s", tc.old, tc.new, err)
+			continue
+		}
+
+		if apply != tc.apply {
+			t.Errorf("checking %q -> %q: expected %v, got %v", tc.old, tc.new, tc.apply, apply)
+		}
+	}
+
+	ver, err := c.Select("1.28.3", []string{"1.27.9", "1.28.5", "1.29.1", "1.28.4"})
+	if err != nil {
+		t.Fatalf("selecting version: %s", err)
+	}
+
+	if ver != "1.28.5" {
+		t.Errorf("expected 1.28.5 to be selected, got %q", ver)
+	}
+}
+
+func TestConstraintRangePrerelease(t *testing.T) {
+	for _, tc := range []struct {
+		c          Constraint
+		old        string
+		candidates []string
+		expect     string
+	}{
+		{Constraint{Type: "semver", Range: "~1.28", AllowPrerelease: true}, "1.28.4", []string{"1.28.5", "1.29.0-rc.1"}, "1.28.5"},
+		{Constraint{Type: "semver", Range: "~1.28", AllowPrerelease: true}, "1.28.4", []string{"1.28.5", "1.28.6-rc.1"}, "1.28.6-rc.1"},
+		{Constraint{Type: "pep440", Range: "~3.11", AllowPrerelease: true}, "3.11.4", []string{"3.11.5", "3.12.0a1"}, "3.11.5"},
+	} {
+		ver, err := tc.c.Select(tc.old, tc.candidates)
+		if err != nil {
+			t.Errorf("selecting from %v in %q: %s", tc.candidates, tc.c.Range, err)
+			continue
+		}
+
+		if ver != tc.expect {
+			t.Errorf("selecting from %v in %q: expected %q, got %q", tc.candidates, tc.c.Range, tc.expect, ver)
+		}
+	}
+}
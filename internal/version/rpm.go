@@ -0,0 +1,181 @@
+package version
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+type (
+	rpmComparer struct{}
+
+	rpmVersion struct {
+		Epoch   int
+		Version string
+		Release string
+	}
+)
+
+var _ comparer = rpmComparer{}
+
+func (r rpmComparer) Compare(oldVersion, newVersion string) (compareResult, error) {
+	oldV, err := r.parse(oldVersion)
+	if err != nil {
+		return compareResultInvalid, fmt.Errorf("parsing old version: %w", err)
+	}
+
+	newV, err := r.parse(newVersion)
+	if err != nil {
+		return compareResultInvalid, fmt.Errorf("parsing new version: %w", err)
+	}
+
+	if oldV.Epoch != newV.Epoch {
+		return compareResultFromCmp(oldV.Epoch - newV.Epoch), nil
+	}
+
+	if cmp := rpmVerCmp(oldV.Version, newV.Version); cmp != 0 {
+		return compareResultFromCmp(cmp), nil
+	}
+
+	if oldV.Release == "" || newV.Release == "" {
+		// Like rpm we only compare releases if both are present
+		return compareResultEqual, nil
+	}
+
+	return compareResultFromCmp(rpmVerCmp(oldV.Release, newV.Release)), nil
+}
+
+func (r rpmComparer) IsPrerelease(newVersion string) (bool, error) {
+	v, err := r.parse(newVersion)
+	if err != nil {
+		return false, fmt.Errorf("parsing version: %w", err)
+	}
+
+	// Pre-releases are either marked with a tilde (1.0~rc1) or follow
+	// the older convention to put the marker into the release
+	// (1.0-0.1.rc1)
+	return strings.Contains(v.Version, "~") ||
+		strings.Contains(v.Release, "~") ||
+		hasPrereleaseMarker(v.Version) ||
+		hasPrereleaseMarker(v.Release), nil
+}
+
+func (rpmComparer) parse(ver string) (v rpmVersion, err error) {
+	if epoch, rest, found := strings.Cut(ver, ":"); found {
+		if v.Epoch, err = strconv.Atoi(epoch); err != nil || v.Epoch < 0 {
+			return v, fmt.Errorf("invalid epoch %q", epoch)
+		}
+		ver = rest
+	}
+
+	v.Version = ver
+	if idx := strings.LastIndex(ver, "-"); idx >= 0 {
+		v.Version, v.Release = ver[:idx], ver[idx+1:]
+		if v.Release == "" {
+			return v, errors.New("empty release")
+		}
+	}
+
+	if v.Version == "" || !isASCIIDigit(v.Version[0]) {
+		return v, fmt.Errorf("version %q does not start with a digit", v.Version)
+	}
+
+	return v, nil
+}
+
+// rpmVerCmp implements the rpmvercmp algorithm: versions are split
+// into alphabetic and numeric segments, tilde sorts before and caret
+// after everything else
+//
+//nolint:gocognit,gocyclo // Keeping the algorithm in one piece makes it comparable to the original
+func rpmVerCmp(a, b string) int {
+	if a == b {
+		return 0
+	}
+
+	isSep := func(c byte) bool { return !isASCIIDigit(c) && !isASCIILetter(c) && c != '~' && c != '^' }
+
+	for a != "" || b != "" {
+		for a != "" && isSep(a[0]) {
+			a = a[1:]
+		}
+		for b != "" && isSep(b[0]) {
+			b = b[1:]
+		}
+
+		// Tilde sorts before everything, even the end of the version
+		if strings.HasPrefix(a, "~") || strings.HasPrefix(b, "~") {
+			if !strings.HasPrefix(a, "~") {
+				return 1
+			}
+			if !strings.HasPrefix(b, "~") {
+				return -1
+			}
+			a, b = a[1:], b[1:]
+			continue
+		}
+
+		// Caret sorts after the end of the version but before everything else
+		if strings.HasPrefix(a, "^") || strings.HasPrefix(b, "^") {
+			switch {
+			case a == "":
+				return -1
+			case b == "":
+				return 1
+			case !strings.HasPrefix(a, "^"):
+				return 1
+			case !strings.HasPrefix(b, "^"):
+				return -1
+			}
+			a, b = a[1:], b[1:]
+			continue
+		}
+
+		if a == "" || b == "" {
+			break
+		}
+
+		isNum := isASCIIDigit(a[0])
+		segA, restA := rpmSegment(a, isNum)
+		segB, restB := rpmSegment(b, isNum)
+		a, b = restA, restB
+
+		if segB == "" {
+			// Segments of different types: numeric is newer than alpha
+			if isNum {
+				return 1
+			}
+			return -1
+		}
+
+		if isNum {
+			segA, segB = strings.TrimLeft(segA, "0"), strings.TrimLeft(segB, "0")
+			if len(segA) != len(segB) {
+				return len(segA) - len(segB)
+			}
+		}
+
+		if cmp := strings.Compare(segA, segB); cmp != 0 {
+			return cmp
+		}
+	}
+
+	switch {
+	case a == "" && b == "":
+		return 0
+	case a != "":
+		return 1
+	default:
+		return -1
+	}
+}
+
+func rpmSegment(s string, numeric bool) (seg, rest string) {
+	i := 0
+	for i < len(s) && ((numeric && isASCIIDigit(s[i])) || (!numeric && isASCIILetter(s[i]))) {
+		i++
+	}
+
+	return s[:i], s[i:]
+}
@@ -0,0 +1,33 @@
+package version
+
+import "testing"
+
+func TestRPMCompareFunc(t *testing.T) {
+	comp := rpmComparer{}
+
+	for _, tc := range []struct {
+		v1, v2 string
+		res    compareResult
+	}{
+		{"1.0", "1.1", compareResultUpgrade},
+		{"1.0-1.el9", "1.0-2.el9", compareResultUpgrade},
+		{"1.0~rc1", "1.0", compareResultUpgrade},
+		{"1.0", "1.0^git1", compareResultUpgrade},
+		{"1.0^git1", "1.0.1", compareResultUpgrade},
+		{"1:1.0", "2.0", compareResultDowngrade},
+		{"1.10", "1.9", compareResultDowngrade},
+		{"1.0a", "1.0.1", compareResultUpgrade},
+		{"2.0.1", "2.0.1a", compareResultUpgrade},
+		{"1.0-1", "1.0", compareResultEqual},
+		{"1.001", "1.1", compareResultEqual},
+	} {
+		res, err := comp.Compare(tc.v1, tc.v2)
+		if err != nil {
+			t.Errorf("Comparing %q to %q: %s", tc.v1, tc.v2, err)
+		}
+
+		if res != tc.res {
+			t.Errorf("Comparing %q to %q: expected %v, got %v", tc.v1, tc.v2, tc.res, res)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package version
+
+import (
+	"cmp"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+)
+
+const semVerLooseSegments = 4
+
+type (
+	semVerLooseComparer struct{}
+
+	semVerLooseVersion struct {
+		Segments []int
+		Modifier string
+	}
+)
+
+var (
+	_ comparer = semVerLooseComparer{}
+
+	semVerLooseRegex = regexp.MustCompile(`^[vV]?(\d+)(?:\.(\d+))?(?:\.(\d+))?(?:\.(\d+))?` +
+		`(?:[-_.]?([0-9A-Za-z][0-9A-Za-z.-]*))?` +
+		`(?:\+[0-9A-Za-z.-]+)?$`)
+)
+
+func (s semVerLooseComparer) Compare(oldVersion, newVersion string) (compareResult, error) {
+	oldV, err := s.parse(oldVersion)
+	if err != nil {
+		return compareResultInvalid, fmt.Errorf("parsing old version: %w", err)
+	}
+
+	newV, err := s.parse(newVersion)
+	if err != nil {
+		return compareResultInvalid, fmt.Errorf("parsing new version: %w", err)
+	}
+
+	if res := slices.Compare(oldV.Segments, newV.Segments); res != 0 {
+		return compareResultFromCmp(res), nil
+	}
+
+	oldRank, oldNum := parseModifier(oldV.Modifier)
+	newRank, newNum := parseModifier(newV.Modifier)
+
+	if res := cmp.Compare(oldRank, newRank); res != 0 {
+		return compareResultFromCmp(res), nil
+	}
+
+	return compareResultFromCmp(cmp.Compare(oldNum, newNum)), nil
+}
+
+func (s semVerLooseComparer) IsPrerelease(newVersion string) (bool, error) {
+	v, err := s.parse(newVersion)
+	if err != nil {
+		return false, fmt.Errorf("parsing version: %w", err)
+	}
+
+	rank, _ := parseModifier(v.Modifier)
+	return rank < prereleaseRankFinal, nil
+}
+
+// parse accepts everything looking like a version: an optional "v"
+// prefix, one to four numeric segments (missing segments are zero,
+// leading zeros are ignored), an optional modifier like "-rc1",
+// "beta.2" or ".dev0" and an optional build metadata which is ignored
+func (semVerLooseComparer) parse(ver string) (semVerLooseVersion, error) {
+	v := semVerLooseVersion{Segments: make([]int, semVerLooseSegments)}
+
+	match := semVerLooseRegex.FindStringSubmatch(ver)
+	if match == nil {
+		return v, fmt.Errorf("version %q is not a loose semantic version", ver)
+	}
+
+	for i := range semVerLooseSegments {
+		if match[i+1] == "" {
+			continue
+		}
+
+		n, err := strconv.Atoi(match[i+1])
+		if err != nil {
+			return v, fmt.Errorf("parsing segment: %w", err)
+		}
+		v.Segments[i] = n
+	}
+
+	v.Modifier = match[semVerLooseSegments+1]
+
+	return v, nil
+}
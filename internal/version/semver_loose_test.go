@@ -0,0 +1,58 @@
+package version
+
+import "testing"
+
+func TestSemVerLooseCompareFunc(t *testing.T) {
+	comp := semVerLooseComparer{}
+
+	for _, tc := range []struct {
+		v1, v2 string
+		res    compareResult
+	}{
+		{"v1.2", "1.2.0", compareResultEqual},
+		{"1.2", "1.3", compareResultUpgrade},
+		{"1.02.003", "1.2.3", compareResultEqual},
+		{"1.2.3.4", "1.2.3.5", compareResultUpgrade},
+		{"1.2.3.4", "1.2.3", compareResultDowngrade},
+		{"2.0.0-rc1", "2.0.0", compareResultUpgrade},
+		{"2.0.0-beta", "2.0.0-rc1", compareResultUpgrade},
+		{"2.0.0-alpha.2", "2.0.0-beta.1", compareResultUpgrade},
+		{"2.0.0.dev0", "2.0.0a1", compareResultUpgrade},
+		{"2.0.0-rc.2", "2.0.0-rc.10", compareResultUpgrade},
+		{"1.0.0+build1", "1.0.0+build2", compareResultEqual},
+		{"v104.0.5112.79", "104.0.5112.80", compareResultUpgrade},
+	} {
+		res, err := comp.Compare(tc.v1, tc.v2)
+		if err != nil {
+			t.Errorf("Comparing %q to %q: %s", tc.v1, tc.v2, err)
+		}
+
+		if res != tc.res {
+			t.Errorf("Comparing %q to %q: expected %v, got %v", tc.v1, tc.v2, tc.res, res)
+		}
+	}
+}
+
+func TestSemVerLooseIsPrerelease(t *testing.T) {
+	comp := semVerLooseComparer{}
+
+	for ver, pre := range map[string]bool{
+		"1.2":          false,
+		"v1.2.3":       false,
+		"1.2.3-rc1":    true,
+		"1.2.3beta":    true,
+		"1.2.3.dev0":   true,
+		"1.2.3-alpha":  true,
+		"1.2.3+build5": false,
+	} {
+		isPre, err := comp.IsPrerelease(ver)
+		if err != nil {
+			t.Errorf("checking %q: %s", ver, err)
+			continue
+		}
+
+		if isPre != pre {
+			t.Errorf("checking %q: expected %v, got %v", ver, pre, isPre)
+		}
+	}
+}
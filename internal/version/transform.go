@@ -0,0 +1,163 @@
+package version
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"text/template"
+)
+
+const (
+	transformTypeLowercase       = "lowercase"
+	transformTypeRegexReplace    = "regex_replace"
+	transformTypeTemplate        = "template"
+	transformTypeTrimPrefix      = "trim_prefix"
+	transformTypeTrimSuffix      = "trim_suffix"
+	transformTypeUnderscoreToDot = "underscore_to_dot"
+)
+
+type (
+	// Transform is an ordered list of steps applied to the version
+	// returned by the fetcher before it is compared and stored
+	Transform []TransformStep
+
+	// TransformStep describes a single modification of the version,
+	// which of the fields are used depends on the Type of the step
+	TransformStep struct {
+		Type string `yaml:"type"`
+
+		Match    string `yaml:"match"`
+		Replace  string `yaml:"replace"`
+		Template string `yaml:"template"`
+		Value    string `yaml:"value"`
+	}
+
+	transformTemplateData struct {
+		Match   []string
+		Version string
+	}
+)
+
+// DefaultTransform is used for catalog entries not specifying their
+// own transform and strips the commonly used "v" prefix
+var DefaultTransform = Transform{{Type: transformTypeTrimPrefix, Value: "v"}}
+
+// Apply executes all steps in order and returns the resulting version
+func (t Transform) Apply(ver string) (string, error) {
+	var err error
+
+	for i, step := range t {
+		if ver, err = step.apply(ver); err != nil {
+			return "", fmt.Errorf("applying transform step %d (%s): %w", i, step.Type, err)
+		}
+	}
+
+	return ver, nil
+}
+
+// Validate checks whether all steps have a known type and the
+// parameters required for that type
+func (t Transform) Validate() error {
+	for i, step := range t {
+		if err := step.validate(); err != nil {
+			return fmt.Errorf("validating transform step %d: %w", i, err)
+		}
+	}
+
+	return nil
+}
+
+func (t TransformStep) apply(ver string) (string, error) {
+	switch t.Type {
+	case transformTypeLowercase:
+		return strings.ToLower(ver), nil
+
+	case transformTypeRegexReplace:
+		re, err := regexp.Compile(t.Match)
+		if err != nil {
+			return "", fmt.Errorf("compiling match: %w", err)
+		}
+		return re.ReplaceAllString(ver, t.Replace), nil
+
+	case transformTypeTemplate:
+		return t.applyTemplate(ver)
+
+	case transformTypeTrimPrefix:
+		return strings.TrimPrefix(ver, t.Value), nil
+
+	case transformTypeTrimSuffix:
+		return strings.TrimSuffix(ver, t.Value), nil
+
+	case transformTypeUnderscoreToDot:
+		return strings.ReplaceAll(ver, "_", "."), nil
+
+	default:
+		return "", fmt.Errorf("unknown transform type %q", t.Type)
+	}
+}
+
+func (t TransformStep) applyTemplate(ver string) (string, error) {
+	data := transformTemplateData{Version: ver}
+
+	if t.Match != "" {
+		re, err := regexp.Compile(t.Match)
+		if err != nil {
+			return "", fmt.Errorf("compiling match: %w", err)
+		}
+
+		if data.Match = re.FindStringSubmatch(ver); data.Match == nil {
+			return "", fmt.Errorf("version %q does not match %q", ver, t.Match)
+		}
+	}
+
+	tpl, err := template.New("transform").Parse(t.Template)
+	if err != nil {
+		return "", fmt.Errorf("parsing template: %w", err)
+	}
+
+	buf := new(bytes.Buffer)
+	if err = tpl.Execute(buf, data); err != nil {
+		return "", fmt.Errorf("executing template: %w", err)
+	}
+
+	return buf.String(), nil
+}
+
+func (t TransformStep) validate() error {
+	switch t.Type {
+	case transformTypeLowercase, transformTypeUnderscoreToDot:
+		return nil
+
+	case transformTypeRegexReplace:
+		if t.Match == "" {
+			return errors.New("regex_replace requires match")
+		}
+		if _, err := regexp.Compile(t.Match); err != nil {
+			return fmt.Errorf("compiling match: %w", err)
+		}
+		return nil
+
+	case transformTypeTemplate:
+		if t.Template == "" {
+			return errors.New("template requires template")
+		}
+		if _, err := template.New("transform").Parse(t.Template); err != nil {
+			return fmt.Errorf("parsing template: %w", err)
+		}
+		if _, err := regexp.Compile(t.Match); err != nil {
+			return fmt.Errorf("compiling match: %w", err)
+		}
+		return nil
+
+	case transformTypeTrimPrefix, transformTypeTrimSuffix:
+		if t.Value == "" {
+			return fmt.Errorf("%s requires value", t.Type)
+		}
+		return nil
+
+	default:
+		return fmt.Errorf("unknown transform type %q", t.Type)
+	}
+}
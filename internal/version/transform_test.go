@@ -0,0 +1,90 @@
+package version
+
+import "testing"
+
+func TestTransformApply(t *testing.T) {
+	for _, tc := range []struct {
+		name      string
+		transform Transform
+		in, out   string
+	}{
+		{"default", DefaultTransform, "v1.2.3", "1.2.3"},
+		{"default without prefix", DefaultTransform, "1.2.3", "1.2.3"},
+		{"empty", Transform{}, "v1.2.3", "v1.2.3"},
+		{
+			"release tag with underscores",
+			Transform{
+				{Type: "trim_prefix", Value: "release-"},
+				{Type: "underscore_to_dot"},
+			},
+			"release-1_2_3", "1.2.3",
+		},
+		{
+			"jdk build number",
+			Transform{
+				{Type: "template", Match: `^jdk-([0-9.]+)\+([0-9]+)$`, Template: "{{ index .Match 1 }}.{{ index .Match 2 }}"},
+			},
+			"jdk-21.0.2+13", "21.0.2.13",
+		},
+		{
+			"regex replace",
+			Transform{{Type: "regex_replace", Match: `^[a-z-]+`, Replace: ""}},
+			"nginx-1.25.3", "1.25.3",
+		},
+		{
+			"lowercase and suffix",
+			Transform{
+				{Type: "lowercase"},
+				{Type: "trim_suffix", Value: "-final"},
+			},
+			"2.0-FINAL", "2.0",
+		},
+		{
+			"template without match",
+			Transform{{Type: "template", Template: "{{ .Version }}.0"}},
+			"1.2", "1.2.0",
+		},
+	} {
+		out, err := tc.transform.Apply(tc.in)
+		if err != nil {
+			t.Errorf("%s: applying transform: %s", tc.name, err)
+			continue
+		}
+
+		if out != tc.out {
+			t.Errorf("%s: expected %q, got %q", tc.name, tc.out, out)
+		}
+	}
+}
+
+func TestTransformApplyNoMatch(t *testing.T) {
+	tf := Transform{{Type: "template", Match: `^jdk-(.*)$`, Template: "{{ index .Match 1 }}"}}
+
+	if _, err := tf.Apply("21.0.2"); err == nil {
+		t.Error("template with non-matching version did not cause error")
+	}
+}
+
+func TestTransformValidate(t *testing.T) {
+	for _, tc := range []struct {
+		name      string
+		transform Transform
+		valid     bool
+	}{
+		{"default", DefaultTransform, true},
+		{"unknown type", Transform{{Type: "uppercase"}}, false},
+		{"regex without match", Transform{{Type: "regex_replace"}}, false},
+		{"invalid regex", Transform{{Type: "regex_replace", Match: "("}}, false},
+		{"template without template", Transform{{Type: "template"}}, false},
+		{"invalid template", Transform{{Type: "template", Template: "{{ .Version"}}, false},
+		{"trim without value", Transform{{Type: "trim_prefix"}}, false},
+	} {
+		err := tc.transform.Validate()
+		if tc.valid && err != nil {
+			t.Errorf("%s: expected valid transform, got %s", tc.name, err)
+		}
+		if !tc.valid && err == nil {
+			t.Errorf("%s: expected validation error", tc.name)
+		}
+	}
+}
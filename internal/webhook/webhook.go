@@ -0,0 +1,250 @@
+// Package webhook contains parsers and signature verification for
+// webhooks sent by code hosting platforms and registries to signal
+// new releases
+package webhook
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+const (
+	maxBodySize = 5 * 1024 * 1024 // 5MiB
+	querySecret = "secret"
+)
+
+type (
+	// Event represents a received webhook signaling a possible new
+	// version within the repository
+	Event struct {
+		Provider   string
+		Repository string
+
+		body   []byte
+		header http.Header
+		secret string
+	}
+
+	provider struct {
+		// parse extracts the repository from the payload and returns
+		// ErrIgnoredEvent for events not signaling a new version
+		parse func(header http.Header, body []byte) (string, error)
+		// verify checks the signature of the payload against the secret
+		verify func(ev *Event, secret string) bool
+	}
+
+	repoPayload struct {
+		Action  string `json:"action"`
+		Ref     string `json:"ref"`
+		RefType string `json:"ref_type"`
+
+		Project struct {
+			PathWithNamespace string `json:"path_with_namespace"`
+		} `json:"project"`
+
+		Repository struct {
+			FullName string `json:"full_name"`
+			RepoName string `json:"repo_name"`
+		} `json:"repository"`
+	}
+
+	querySecretKey struct{}
+)
+
+var (
+	// ErrIgnoredEvent signalizes the webhook is valid but does not
+	// signal a new version (for example a push to a branch)
+	ErrIgnoredEvent = errors.New("event does not signal a new version")
+	// ErrUnknownProvider signalizes the given provider is not supported
+	ErrUnknownProvider = errors.New("unknown provider")
+
+	providers = map[string]provider{
+		"dockerhub": {parse: parseDockerHub, verify: verifyQuerySecret},
+		"gitea":     {parse: parseGitHubLike("X-Gitea-Event"), verify: verifyHMACHeader("X-Gitea-Signature", "")},
+		"github":    {parse: parseGitHubLike("X-GitHub-Event"), verify: verifyHMACHeader("X-Hub-Signature-256", "sha256=")},
+		"gitlab":    {parse: parseGitLab, verify: verifyTokenHeader("X-Gitlab-Token")},
+	}
+)
+
+// Parse reads the webhook request for the given provider and extracts
+// the repository the event belongs to. The signature is not checked
+// as the secret depends on the catalog entry, use Verify for this.
+func Parse(providerName string, r *http.Request) (*Event, error) {
+	p, ok := providers[providerName]
+	if !ok {
+		return nil, ErrUnknownProvider
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxBodySize))
+	if err != nil {
+		return nil, fmt.Errorf("reading body: %w", err)
+	}
+
+	repo, err := p.parse(r.Header, body)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Event{
+		Provider:   providerName,
+		Repository: repo,
+
+		body:   body,
+		header: r.Header,
+		secret: querySecretFromContext(r.Context()),
+	}, nil
+}
+
+// RedactQuerySecret moves the secret passed as query parameter (used
+// by Docker Hub as it cannot sign its webhooks) from the URL into the
+// request context. It needs to wrap the access logger to keep the
+// secret out of the logs.
+func RedactQuerySecret(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		params := r.URL.Query()
+		if !params.Has(querySecret) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		secret := params.Get(querySecret)
+		params.Del(querySecret)
+
+		r = r.Clone(context.WithValue(r.Context(), querySecretKey{}, secret))
+		r.URL.RawQuery = params.Encode()
+		r.RequestURI = r.URL.RequestURI()
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Verify checks whether the event was signed using the given secret
+func (e Event) Verify(secret string) bool {
+	if secret == "" {
+		return false
+	}
+
+	return providers[e.Provider].verify(&e, secret)
+}
+
+func parseDockerHub(_ http.Header, body []byte) (string, error) {
+	var payload repoPayload
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return "", fmt.Errorf("decoding payload: %w", err)
+	}
+
+	if payload.Repository.RepoName == "" {
+		return "", errors.New("payload contains no repository")
+	}
+
+	return payload.Repository.RepoName, nil
+}
+
+// parseGitHubLike handles the payloads of GitHub and Gitea which are
+// identical for the events used here
+func parseGitHubLike(eventHeader string) func(http.Header, []byte) (string, error) {
+	return func(header http.Header, body []byte) (string, error) {
+		var payload repoPayload
+		if err := json.Unmarshal(body, &payload); err != nil {
+			return "", fmt.Errorf("decoding payload: %w", err)
+		}
+
+		switch header.Get(eventHeader) {
+		case "create":
+			if payload.RefType != "tag" {
+				return "", ErrIgnoredEvent
+			}
+
+		case "push":
+			if !strings.HasPrefix(payload.Ref, "refs/tags/") {
+				return "", ErrIgnoredEvent
+			}
+
+		case "release":
+			if payload.Action != "published" {
+				return "", ErrIgnoredEvent
+			}
+
+		default:
+			return "", ErrIgnoredEvent
+		}
+
+		if payload.Repository.FullName == "" {
+			return "", errors.New("payload contains no repository")
+		}
+
+		return payload.Repository.FullName, nil
+	}
+}
+
+func parseGitLab(header http.Header, body []byte) (string, error) {
+	switch header.Get("X-Gitlab-Event") {
+	case "Release Hook", "Tag Push Hook":
+		// Those are the events we're interested in
+
+	default:
+		return "", ErrIgnoredEvent
+	}
+
+	var payload repoPayload
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return "", fmt.Errorf("decoding payload: %w", err)
+	}
+
+	if payload.Project.PathWithNamespace == "" {
+		return "", errors.New("payload contains no project")
+	}
+
+	return payload.Project.PathWithNamespace, nil
+}
+
+// querySecretFromContext returns the secret removed from the URL by
+// RedactQuerySecret
+func querySecretFromContext(ctx context.Context) string {
+	secret, _ := ctx.Value(querySecretKey{}).(string)
+	return secret
+}
+
+// verifyHMACHeader checks the hex encoded HMAC-SHA256 of the body
+// given in the header
+func verifyHMACHeader(header, prefix string) func(*Event, string) bool {
+	return func(ev *Event, secret string) bool {
+		sig, ok := strings.CutPrefix(ev.header.Get(header), prefix)
+		if !ok {
+			return false
+		}
+
+		given, err := hex.DecodeString(sig)
+		if err != nil {
+			return false
+		}
+
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write(ev.body) //nolint:errcheck,gosec // Writing to a hash never fails
+
+		return hmac.Equal(given, mac.Sum(nil))
+	}
+}
+
+// verifyQuerySecret checks the secret given in the URL as Docker Hub
+// does not support signing its webhooks
+func verifyQuerySecret(ev *Event, secret string) bool {
+	return subtle.ConstantTimeCompare([]byte(ev.secret), []byte(secret)) == 1
+}
+
+// verifyTokenHeader checks the plain secret given in the header as
+// GitLab does not sign its webhooks
+func verifyTokenHeader(header string) func(*Event, string) bool {
+	return func(ev *Event, secret string) bool {
+		return subtle.ConstantTimeCompare([]byte(ev.header.Get(header)), []byte(secret)) == 1
+	}
+}
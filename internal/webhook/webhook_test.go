@@ -0,0 +1,154 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const testSecret = "s3cr3t"
+
+func TestParseAndVerify(t *testing.T) {
+	sign := func(body string) string {
+		mac := hmac.New(sha256.New, []byte(testSecret))
+		mac.Write([]byte(body))
+		return hex.EncodeToString(mac.Sum(nil))
+	}
+
+	for _, tc := range []struct {
+		name     string
+		provider string
+		url      string
+		header   map[string]string
+		body     string
+		repo     string
+		err      error
+		verified bool
+	}{
+		{
+			name:     "github release",
+			provider: "github",
+			header:   map[string]string{"X-GitHub-Event": "release", "X-Hub-Signature-256": "sha256=" + sign(`{"action":"published","repository":{"full_name":"Luzifer/go-latestver"}}`)},
+			body:     `{"action":"published","repository":{"full_name":"Luzifer/go-latestver"}}`,
+			repo:     "Luzifer/go-latestver",
+			verified: true,
+		},
+		{
+			name:     "github tag push with wrong signature",
+			provider: "github",
+			header:   map[string]string{"X-GitHub-Event": "push", "X-Hub-Signature-256": "sha256=" + sign("other")},
+			body:     `{"ref":"refs/tags/v1.0.0","repository":{"full_name":"Luzifer/go-latestver"}}`,
+			repo:     "Luzifer/go-latestver",
+			verified: false,
+		},
+		{
+			name:     "github branch push",
+			provider: "github",
+			header:   map[string]string{"X-GitHub-Event": "push"},
+			body:     `{"ref":"refs/heads/main","repository":{"full_name":"Luzifer/go-latestver"}}`,
+			err:      ErrIgnoredEvent,
+		},
+		{
+			name:     "gitea tag create",
+			provider: "gitea",
+			header:   map[string]string{"X-Gitea-Event": "create", "X-Gitea-Signature": sign(`{"ref":"v1.0.0","ref_type":"tag","repository":{"full_name":"org/repo"}}`)},
+			body:     `{"ref":"v1.0.0","ref_type":"tag","repository":{"full_name":"org/repo"}}`,
+			repo:     "org/repo",
+			verified: true,
+		},
+		{
+			name:     "gitlab tag push",
+			provider: "gitlab",
+			header:   map[string]string{"X-Gitlab-Event": "Tag Push Hook", "X-Gitlab-Token": testSecret},
+			body:     `{"ref":"refs/tags/v1.0.0","project":{"path_with_namespace":"group/sub/project"}}`,
+			repo:     "group/sub/project",
+			verified: true,
+		},
+		{
+			name:     "gitlab pipeline",
+			provider: "gitlab",
+			header:   map[string]string{"X-Gitlab-Event": "Pipeline Hook", "X-Gitlab-Token": testSecret},
+			body:     `{}`,
+			err:      ErrIgnoredEvent,
+		},
+		{
+			name:     "dockerhub push",
+			provider: "dockerhub",
+			url:      "?secret=" + testSecret,
+			body:     `{"push_data":{"tag":"latest"},"repository":{"repo_name":"luzifer/latestver"}}`,
+			repo:     "luzifer/latestver",
+			verified: true,
+		},
+		{
+			name:     "dockerhub push without secret",
+			provider: "dockerhub",
+			body:     `{"push_data":{"tag":"latest"},"repository":{"repo_name":"luzifer/latestver"}}`,
+			repo:     "luzifer/latestver",
+			verified: false,
+		},
+		{
+			name:     "unknown provider",
+			provider: "bitbucket",
+			body:     `{}`,
+			err:      ErrUnknownProvider,
+		},
+	} {
+		req := httptest.NewRequest(http.MethodPost, "/v1/webhook/"+tc.provider+tc.url, strings.NewReader(tc.body))
+		for k, v := range tc.header {
+			req.Header.Set(k, v)
+		}
+
+		var (
+			ev  *Event
+			err error
+		)
+		RedactQuerySecret(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+			ev, err = Parse(tc.provider, r)
+		})).ServeHTTP(httptest.NewRecorder(), req)
+		if tc.err != nil {
+			if !errors.Is(err, tc.err) {
+				t.Errorf("%s: expected error %q, got %v", tc.name, tc.err, err)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Fatalf("%s: parsing webhook: %s", tc.name, err)
+		}
+
+		if ev.Repository != tc.repo {
+			t.Errorf("%s: expected repository %q, got %q", tc.name, tc.repo, ev.Repository)
+		}
+
+		if v := ev.Verify(testSecret); v != tc.verified {
+			t.Errorf("%s: expected verification result %v, got %v", tc.name, tc.verified, v)
+		}
+
+		if ev.Verify("") {
+			t.Errorf("%s: empty secret must never verify", tc.name)
+		}
+	}
+}
+
+func TestRedactQuerySecret(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/v1/webhook/dockerhub?foo=bar&secret="+testSecret, strings.NewReader("{}"))
+
+	RedactQuerySecret(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		if strings.Contains(r.URL.String(), testSecret) || strings.Contains(r.RequestURI, testSecret) {
+			t.Errorf("secret still contained in URL %q / %q", r.URL, r.RequestURI)
+		}
+
+		if r.URL.Query().Get("foo") != "bar" {
+			t.Errorf("other query parameters were removed: %q", r.URL.RawQuery)
+		}
+
+		if s := querySecretFromContext(r.Context()); s != testSecret {
+			t.Errorf("expected secret %q in context, got %q", testSecret, s)
+		}
+	})).ServeHTTP(httptest.NewRecorder(), req)
+}
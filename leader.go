@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"sync/atomic"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/Luzifer/go-latestver/internal/helpers"
+)
+
+const (
+	instanceIDRandomBytes = 4
+	leaderLeaseName       = "scheduler"
+	leaderRenewalsPerTTL  = 3
+)
+
+var (
+	// instanceID identifies this instance when competing for the
+	// scheduler lease with other replicas
+	instanceID = newInstanceID()
+
+	// isLeader is true while this instance holds the scheduler lease
+	// and therefore is responsible for running scheduled checks
+	isLeader atomic.Bool
+)
+
+// leaderElection continuously tries to acquire or renew the scheduler
+// lease so only one of multiple replicas sharing a database executes
+// the scheduled checks. The lease is released when the context is
+// cancelled (after the running checks finished on shutdown) to allow
+// another replica to take over immediately.
+func leaderElection(ctx context.Context) {
+	if cfg.LeaderLeaseTTL <= 0 {
+		// Election is disabled, this instance is always responsible
+		isLeader.Store(true)
+		return
+	}
+
+	for {
+		acquired, err := storage.Leases.Acquire(leaderLeaseName, instanceID, cfg.LeaderLeaseTTL)
+		if err != nil {
+			// When in doubt we must assume someone else took over
+			log.WithError(err).Error("Unable to acquire scheduler lease")
+			acquired = false
+		}
+
+		switch was := isLeader.Swap(acquired); {
+		case !was && acquired:
+			log.WithField("instance", instanceID).Info("Acquired scheduler leadership")
+		case was && !acquired:
+			log.WithField("instance", instanceID).Info("Lost scheduler leadership")
+		}
+
+		select {
+		case <-ctx.Done():
+			if isLeader.Swap(false) {
+				helpers.LogIfErr(storage.Leases.Release(leaderLeaseName, instanceID), "releasing scheduler lease")
+			}
+			return
+
+		case <-time.After(cfg.LeaderLeaseTTL / leaderRenewalsPerTTL):
+			// Renew the lease
+		}
+	}
+}
+
+func newInstanceID() string {
+	host, err := os.Hostname()
+	if err != nil {
+		host = "unknown"
+	}
+
+	suffix := make([]byte, instanceIDRandomBytes)
+	_, _ = rand.Read(suffix) // Never returns an error
+
+	return fmt.Sprintf("%s-%s", host, hex.EncodeToString(suffix))
+}
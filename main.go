@@ -1,9 +1,14 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"net/http"
 	"os"
+	"os/signal"
+	"sync"
+	"syscall"
 	"time"
 
 	filehelper "github.com/Luzifer/go_helpers/file"
@@ -15,21 +20,42 @@ import (
 
 	"github.com/Luzifer/go-latestver/internal/config"
 	"github.com/Luzifer/go-latestver/internal/database"
+	"github.com/Luzifer/go-latestver/internal/webhook"
 )
 
 var (
 	cfg = struct {
-		BaseURL           string        `flag:"base-url" default:"https://example.com/" description:"Base-URL the application is reachable at"`
-		Config            string        `flag:"config,c" default:"config.yaml" description:"Configuration file with catalog entries"`
-		Listen            string        `flag:"listen" default:":3000" description:"Port/IP to listen on"`
-		LogLevel          string        `flag:"log-level" default:"info" description:"Log level (debug, info, warn, error, fatal)"`
-		CheckDistribution time.Duration `flag:"check-distribution" default:"1h" description:"Checks are executed at static times every [value]"`
-		Storage           string        `flag:"storage" default:"sqlite" description:"Storage adapter to use (mysql, postgres, sqlite)"`
-		StorageDSN        string        `flag:"storage-dsn" default:"file::memory:?cache=shared" description:"DSN to connect to the database"`
-		VersionAndExit    bool          `flag:"version" default:"false" description:"Prints current version and exits"`
-		WatchConfig       bool          `flag:"watch-config" default:"true" description:"Whether to watch the config file for changes"`
+		AdminImportMaxSize         int64         `flag:"admin-import-max-size" default:"104857600" description:"Maximum size in bytes of a backup uploaded to the admin import API"`
+		AdminToken                 string        `flag:"admin-token" default:"" description:"Bearer token to access the admin API (backup export / import, forced checks), admin API is disabled if empty"`
+		BackoffMax                 time.Duration `flag:"backoff-max" default:"24h" description:"Maximum delay between checks of a repeatedly failing catalog entry (0 = no backoff)"`
+		BaseURL                    string        `flag:"base-url" default:"https://example.com/" description:"Base-URL the application is reachable at"`
+		Config                     string        `flag:"config,c" default:"config.yaml" description:"Configuration file with catalog entries"`
+		LeaderLeaseTTL             time.Duration `flag:"leader-lease-ttl" default:"30s" description:"How long the scheduler leadership is held without renewal when running multiple replicas on one database (0 = disable leader election)"`
+		ShutdownTimeout            time.Duration `flag:"shutdown-timeout" default:"25s" description:"How long to wait for running requests and checks to finish on shutdown"`
+		LogMaxAge                  time.Duration `flag:"log-max-age" default:"0" description:"Remove log entries older than [value] (0 = keep forever)"`
+		LogMaxEntries              int           `flag:"log-max-entries" default:"0" description:"Keep at most [value] log entries per catalog entry (0 = no limit)"`
+		Listen                     string        `flag:"listen" default:":3000" description:"Port/IP to listen on"`
+		LogLevel                   string        `flag:"log-level" default:"info" description:"Log level (debug, info, warn, error, fatal)"`
+		CheckConcurrency           int           `flag:"check-concurrency" default:"10" description:"How many catalog entries to check in parallel"`
+		CheckConcurrencyPerFetcher int           `flag:"check-concurrency-per-fetcher" default:"5" description:"How many catalog entries using the same fetcher to check in parallel (0 = no limit)"`
+		CheckHistoryMaxAge         time.Duration `flag:"check-history-max-age" default:"168h" description:"Remove check attempts older than [value] (0 = keep forever)"`
+		CheckDistribution          time.Duration `flag:"check-distribution" default:"1h" description:"Checks are executed at static times every [value] unless configured in the config file"`
+		CheckTimeout               time.Duration `flag:"check-timeout" default:"1m" description:"Timeout for checking a single catalog entry"`
+		PruneOrphans               bool          `flag:"prune-orphans" default:"false" description:"Remove logs, checks and meta of catalog entries no longer present in the config"`
+		Storage                    string        `flag:"storage" default:"sqlite" description:"Storage adapter to use (bolt, mysql, postgres, sqlite)"`
+		StorageDSN                 string        `flag:"storage-dsn" default:"file::memory:?cache=shared" description:"DSN to connect to the database"`
+		VersionAndExit             bool          `flag:"version" default:"false" description:"Prints current version and exits"`
+		WatchConfig                bool          `flag:"watch-config" default:"true" description:"Whether to watch the config file for changes"`
 	}{}
 
+	// appCtx is cancelled when the application is asked to shut down
+	// and is used as parent for all checks
+	appCtx = context.Background()
+
+	// backgroundChecks tracks checks running outside the scheduler and
+	// the HTTP handlers to wait for them on shutdown
+	backgroundChecks sync.WaitGroup
+
 	configFile = config.New()
 	router     *mux.Router
 	storage    *database.Client
@@ -53,7 +79,11 @@ func initApp() error {
 }
 
 func main() {
-	var err error
+	var (
+		cancel context.CancelFunc
+		err    error
+	)
+
 	if err = initApp(); err != nil {
 		log.WithError(err).Fatal("initializing app")
 	}
@@ -71,53 +101,50 @@ func main() {
 		log.WithError(err).Fatal("Configuration is not valid")
 	}
 
-	if cfg.WatchConfig {
-		fsWatch, err := filehelper.NewWatcherWithOpts(
-			cfg.Config,
-			filehelper.WatcherOpts{
-				FollowSymlinks: true,
-			},
-			time.Minute,
-			filehelper.WatcherCheckPresence,
-			filehelper.WatcherCheckSize,
-			filehelper.WatcherCheckMtime,
-		)
-		if err != nil {
-			log.WithError(err).Fatal("creating config file watcher")
-		}
-		go reloadConfigOnChange(fsWatch)
-	}
-
 	storage, err = database.NewClient(cfg.Storage, cfg.StorageDSN)
 	if err != nil {
 		log.WithError(err).Fatal("Unable to connect to database")
 	}
 
+	// First argument is the name of the binary itself
+	if args := rconfig.Args()[1:]; len(args) > 0 {
+		runCommand(args[0], args[1:])
+		return
+	}
+
+	if cfg.WatchConfig {
+		startConfigWatcher()
+	}
+
+	appCtx, cancel = signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	// The leadership must be kept until the running checks are finished
+	// on shutdown so the election is not bound to the appCtx
+	leaderCtx, stopLeader := context.WithCancel(context.Background())
+	defer stopLeader()
+
+	leaderDone := make(chan struct{})
+	go func() {
+		defer close(leaderDone)
+		leaderElection(leaderCtx)
+	}()
+
 	scheduler := cron.New()
 	if _, err = scheduler.AddFunc(fmt.Sprintf("@every %s", schedulerInterval), schedulerRun); err != nil {
 		log.WithError(err).Fatal("registering cron entry")
 	}
+	if _, err = scheduler.AddFunc(fmt.Sprintf("@every %s", pruneInterval), pruneRun); err != nil {
+		log.WithError(err).Fatal("registering prune cron entry")
+	}
 	scheduler.Start()
 
-	router = mux.NewRouter()
-	router.HandleFunc("/v1/healthz", func(w http.ResponseWriter, _ *http.Request) { w.WriteHeader(http.StatusOK) }).Methods(http.MethodGet)
-	router.HandleFunc("/v1/catalog", handleCatalogList).Methods(http.MethodGet)
-	router.HandleFunc("/v1/catalog/{name}/{tag}", handleCatalogGet).Methods(http.MethodGet)
-	router.HandleFunc("/v1/catalog/{name}/{tag}/log", handleLog).Methods(http.MethodGet)
-	router.HandleFunc("/v1/catalog/{name}/{tag}/version", handleCatalogGetVersion).Methods(http.MethodGet)
-	router.HandleFunc("/v1/log", handleLog).Methods(http.MethodGet)
-
-	router.HandleFunc("/{name}/{tag}.svg", handleBadge).Methods(http.MethodGet).Name("catalog-entry-badge")
-	router.HandleFunc("/{name}/{tag}/log.rss", handleLogFeed).Methods(http.MethodGet).Name("catalog-entry-rss")
-	router.HandleFunc("/log.rss", handleLogFeed).Methods(http.MethodGet).Name("log-rss")
-
-	router.HandleFunc("/", handleSinglePage).Methods(http.MethodGet).Name("catalog")
-	router.HandleFunc("/{name}/{tag}", handleSinglePage).Methods(http.MethodGet).Name("catalog-entry")
-	router.PathPrefix("/").HandlerFunc(handleSinglePage)
+	router = newRouter()
 
 	var handler http.Handler = router
 	handler = httphelper.GzipHandler(handler)
 	handler = httphelper.NewHTTPLogHandlerWithLogger(handler, log.StandardLogger())
+	handler = webhook.RedactQuerySecret(handler)
 
 	server := &http.Server{
 		Addr:              cfg.Listen,
@@ -125,9 +152,43 @@ func main() {
 		ReadHeaderTimeout: time.Second,
 	}
 
-	if err := server.ListenAndServe(); err != nil {
-		log.WithError(err).Fatal("HTTP server exited unclean")
-	}
+	go func() {
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.WithError(err).Fatal("HTTP server exited unclean")
+		}
+	}()
+
+	<-appCtx.Done()
+	log.Info("Shutting down")
+
+	shutdown(server, scheduler, stopLeader, leaderDone)
+}
+
+// newRouter creates the router containing all API and frontend routes
+func newRouter() *mux.Router {
+	r := mux.NewRouter()
+	r.HandleFunc("/v1/healthz", func(w http.ResponseWriter, _ *http.Request) { w.WriteHeader(http.StatusOK) }).Methods(http.MethodGet)
+	r.HandleFunc("/v1/admin/export", requireAdminToken(handleAdminExport)).Methods(http.MethodGet)
+	r.HandleFunc("/v1/admin/import", requireAdminToken(handleAdminImport)).Methods(http.MethodPost)
+	r.HandleFunc("/v1/catalog", handleCatalogList).Methods(http.MethodGet)
+	r.HandleFunc("/v1/catalog/check", requireAdminToken(handleCatalogCheckAll)).Methods(http.MethodPost)
+	r.HandleFunc("/v1/catalog/{name}/{tag}", handleCatalogGet).Methods(http.MethodGet)
+	r.HandleFunc("/v1/catalog/{name}/{tag}/check", requireAdminToken(handleCatalogCheck)).Methods(http.MethodPost)
+	r.HandleFunc("/v1/catalog/{name}/{tag}/checks", handleCatalogChecks).Methods(http.MethodGet)
+	r.HandleFunc("/v1/catalog/{name}/{tag}/log", handleLog).Methods(http.MethodGet)
+	r.HandleFunc("/v1/catalog/{name}/{tag}/version", handleCatalogGetVersion).Methods(http.MethodGet)
+	r.HandleFunc("/v1/log", handleLog).Methods(http.MethodGet)
+	r.HandleFunc("/v1/webhook/{provider}", handleWebhook).Methods(http.MethodPost)
+
+	r.HandleFunc("/{name}/{tag}.svg", handleBadge).Methods(http.MethodGet).Name("catalog-entry-badge")
+	r.HandleFunc("/{name}/{tag}/log.rss", handleLogFeed).Methods(http.MethodGet).Name("catalog-entry-rss")
+	r.HandleFunc("/log.rss", handleLogFeed).Methods(http.MethodGet).Name("log-rss")
+
+	r.HandleFunc("/", handleSinglePage).Methods(http.MethodGet).Name("catalog")
+	r.HandleFunc("/{name}/{tag}", handleSinglePage).Methods(http.MethodGet).Name("catalog-entry")
+	r.PathPrefix("/").HandlerFunc(handleSinglePage)
+
+	return r
 }
 
 func reloadConfigOnChange(fsWatch *filehelper.Watcher) {
@@ -151,3 +212,75 @@ func reloadConfigOnChange(fsWatch *filehelper.Watcher) {
 		log.Info("reloaded config on fs-event")
 	}
 }
+
+// runCommand executes the command given on the commandline instead of
+// running the server
+func runCommand(command string, args []string) {
+	fn, ok := map[string]func(args []string) error{
+		"export": exportDatabase,
+		"import": importDatabase,
+		"prune":  func([]string) error { return pruneDatabase() },
+	}[command]
+	if !ok {
+		log.Fatalf("Unknown command %q", command)
+	}
+
+	if err := fn(args); err != nil {
+		log.WithError(err).Fatalf("Unable to execute command %q", command)
+	}
+}
+
+// shutdown stops accepting new requests and checks, waits for the
+// running ones to finish, gives up the scheduler leadership and closes
+// the database afterwards
+func shutdown(server *http.Server, scheduler *cron.Cron, stopLeader context.CancelFunc, leaderDone <-chan struct{}) {
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancel()
+
+	if err := server.Shutdown(ctx); err != nil {
+		log.WithError(err).Error("shutting down HTTP server")
+	}
+
+	checksDone := make(chan struct{})
+	go func() {
+		<-scheduler.Stop().Done()
+		backgroundChecks.Wait()
+		close(checksDone)
+	}()
+
+	select {
+	case <-checksDone:
+		// Everything is finished, we can safely close the database
+
+	case <-ctx.Done():
+		log.Error("Checks did not finish in time, closing database anyway")
+	}
+
+	// Releasing the lease earlier would allow another replica to check
+	// the same entries while our checks are still writing their results
+	stopLeader()
+	<-leaderDone
+
+	if err := storage.Close(); err != nil {
+		log.WithError(err).Error("closing database")
+	}
+}
+
+// startConfigWatcher watches the config file for changes and reloads
+// it in the background
+func startConfigWatcher() {
+	fsWatch, err := filehelper.NewWatcherWithOpts(
+		cfg.Config,
+		filehelper.WatcherOpts{
+			FollowSymlinks: true,
+		},
+		time.Minute,
+		filehelper.WatcherCheckPresence,
+		filehelper.WatcherCheckSize,
+		filehelper.WatcherCheckMtime,
+	)
+	if err != nil {
+		log.WithError(err).Fatal("creating config file watcher")
+	}
+	go reloadConfigOnChange(fsWatch)
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/Luzifer/go-latestver/internal/database"
+)
+
+const pruneInterval = time.Hour
+
+// pruneDatabase removes check attempts, log entries and data of removed
+// catalog entries according to the retention settings
+func pruneDatabase() error {
+	res, err := storage.Prune(database.PruneOptions{
+		Catalog:       configFile.Catalog,
+		RemoveOrphans: cfg.PruneOrphans,
+
+		CheckMaxAge:   cfg.CheckHistoryMaxAge,
+		LogMaxAge:     cfg.LogMaxAge,
+		LogMaxEntries: cfg.LogMaxEntries,
+	})
+	if err != nil {
+		return fmt.Errorf("pruning database: %w", err)
+	}
+
+	log.WithFields(log.Fields{
+		"checks": res.Checks,
+		"logs":   res.Logs,
+		"metas":  res.Metas,
+	}).Info("Pruned database")
+
+	return nil
+}
+
+// pruneRun is executed periodically by the scheduler to enforce the
+// retention settings
+func pruneRun() {
+	if !isLeader.Load() {
+		// Only one of the replicas needs to do this
+		return
+	}
+
+	if err := pruneDatabase(); err != nil {
+		log.WithError(err).Error("Unable to prune database")
+	}
+}
@@ -5,57 +5,221 @@ import (
 	"crypto/md5" //#nosec:G501 // Used to derive a static jitter checksum, not cryptographically
 	"fmt"
 	"math"
-	"strings"
+	"sync"
 	"time"
 
+	"github.com/robfig/cron/v3"
 	log "github.com/sirupsen/logrus"
 
 	"github.com/Luzifer/go-latestver/internal/database"
 	"github.com/Luzifer/go-latestver/internal/fetcher"
+	"github.com/Luzifer/go-latestver/internal/helpers"
 )
 
-const schedulerInterval = time.Minute
+const (
+	entryLeasePrefix        = "check:"
+	entryLeaseRetryInterval = time.Second
+	schedulerInterval       = time.Minute
+)
+
+type (
+	// checkLimiter restricts the number of concurrent checks in total
+	// and using the same fetcher
+	checkLimiter struct {
+		perFetcher int
+		slots      map[string]chan struct{}
+		total      chan struct{}
+		lock       sync.Mutex
+	}
+)
+
+var (
+	// checkLimits is shared by scheduler runs, forced checks and
+	// webhooks to enforce the concurrency settings for the whole process
+	checkLimits = sync.OnceValue(func() *checkLimiter {
+		return newCheckLimiter(cfg.CheckConcurrency, cfg.CheckConcurrencyPerFetcher)
+	})
 
-var schedulerRunActive bool
+	// entryLocks holds a *sync.Mutex per catalog entry key to prevent
+	// scheduled and forced checks of the same entry to run in parallel
+	// within this instance, see lockEntry for other replicas
+	entryLocks sync.Map
+
+	// forceCheckAllLock ensures only one forced check of all entries
+	// is running at the same time
+	forceCheckAllLock sync.Mutex
+
+	// schedulerRunLock ensures only one scheduler run is active as a run
+	// might take longer than the scheduler interval
+	schedulerRunLock sync.Mutex
+)
+
+func newCheckLimiter(total, perFetcher int) *checkLimiter {
+	return &checkLimiter{
+		perFetcher: perFetcher,
+		slots:      make(map[string]chan struct{}),
+		total:      make(chan struct{}, max(total, 1)),
+	}
+}
 
 func schedulerRun() {
-	if schedulerRunActive {
+	if !schedulerRunLock.TryLock() {
+		log.Debug("Previous scheduler run still active, skipping")
+		return
+	}
+	defer schedulerRunLock.Unlock()
+
+	if !isLeader.Load() {
+		log.Trace("Not holding scheduler leadership, skipping")
 		return
 	}
-	schedulerRunActive = true
-	defer func() { schedulerRunActive = false }()
 
-	for i := range configFile.Catalog {
-		ce := configFile.Catalog[i]
-		if err := checkForUpdates(&ce); err != nil {
-			log.WithField("entry", ce.Key()).WithError(err).Error("Unable to update entry")
+	runChecks(configFile.Catalog, func(ctx context.Context, ce *database.CatalogEntry) error {
+		if !isLeader.Load() {
+			// Leadership was lost during the run, another instance will
+			// take over checking the remaining entries
+			return nil
 		}
+
+		return checkForUpdates(ctx, ce)
+	})
+}
+
+// forceCheckAll checks all catalog entries regardless of their next
+// check time and returns false if a forced check is already running
+func forceCheckAll() bool {
+	if !forceCheckAllLock.TryLock() {
+		return false
 	}
+
+	catalog := configFile.Catalog
+	backgroundChecks.Go(func() {
+		defer forceCheckAllLock.Unlock()
+		runChecks(catalog, forceCheck)
+	})
+
+	return true
 }
 
-func checkForUpdates(ce *database.CatalogEntry) error {
-	logger := log.WithField("entry", ce.Key())
+// runChecks executes the check for all given catalog entries using a
+// pool of workers, the number of checks running at the same time is
+// limited by the concurrency settings across all runs
+func runChecks(catalog []database.CatalogEntry, check func(context.Context, *database.CatalogEntry) error) {
+	var (
+		entries = make(chan database.CatalogEntry)
+		wg      sync.WaitGroup
+	)
 
+	for range max(cfg.CheckConcurrency, 1) {
+		wg.Go(func() {
+			for ce := range entries {
+				release := checkLimits().acquire(ce.Fetcher)
+				checkEntry(&ce, check)
+				release()
+			}
+		})
+	}
+
+	for i := range catalog {
+		entries <- catalog[i]
+	}
+	close(entries)
+
+	wg.Wait()
+}
+
+func checkEntry(ce *database.CatalogEntry, check func(context.Context, *database.CatalogEntry) error) {
+	ctx, cancel := context.WithTimeout(appCtx, cfg.CheckTimeout)
+	defer cancel()
+
+	if err := check(ctx, ce); err != nil {
+		log.WithField("entry", ce.Key()).WithError(err).Error("Unable to update entry")
+	}
+}
+
+// checkForUpdates checks the catalog entry if its next check time has
+// been reached
+func checkForUpdates(ctx context.Context, ce *database.CatalogEntry) error {
+	// The next check time is looked up before locking the entry to not
+	// acquire the entry lease for every entry in every scheduler run
 	cm, err := storage.Catalog.GetMeta(ce)
 	if err != nil {
 		return fmt.Errorf("getting catalog meta: %w", err)
 	}
 
-	nct := nextCheckTime(ce, cm.LastChecked)
-	logger = logger.WithFields(log.Fields{
-		"last": cm.LastChecked,
-		"next": nct,
-	})
-	logger.Trace("Next check time found")
-	if nct.After(time.Now()) {
-		// Not yet ready to check
+	if !checkDue(ce, cm) {
 		return nil
 	}
 
+	unlock, err := lockEntry(ctx, ce)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	// A forced check or another replica might have checked the entry
+	// while waiting for the lock
+	if cm, err = storage.Catalog.GetMeta(ce); err != nil {
+		return fmt.Errorf("getting catalog meta: %w", err)
+	}
+
+	if !checkDue(ce, cm) {
+		return nil
+	}
+
+	return executeCheck(ctx, ce, cm)
+}
+
+// checkDue checks whether the next check time of the catalog entry
+// has been reached
+func checkDue(ce *database.CatalogEntry, cm *database.CatalogMeta) bool {
+	nct := backoffCheckTime(ce, cm, nextCheckTime(ce, cm.LastChecked))
+	log.WithFields(log.Fields{
+		"entry":    ce.Key(),
+		"failures": cm.ConsecutiveFailures,
+		"last":     cm.LastChecked,
+		"next":     nct,
+	}).Trace("Next check time found")
+
+	return !nct.After(time.Now())
+}
+
+// forceCheck checks the catalog entry immediately without respecting
+// its next check time
+func forceCheck(ctx context.Context, ce *database.CatalogEntry) error {
+	unlock, err := lockEntry(ctx, ce)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	cm, err := storage.Catalog.GetMeta(ce)
+	if err != nil {
+		return fmt.Errorf("getting catalog meta: %w", err)
+	}
+
+	return executeCheck(ctx, ce, cm)
+}
+
+// executeCheck fetches the version of the catalog entry and updates
+// the stored meta and log accordingly
+func executeCheck(ctx context.Context, ce *database.CatalogEntry, cm *database.CatalogMeta) error {
+	logger := log.WithField("entry", ce.Key())
 	logger.Debug("Checking for updates")
 
-	ver, vertime, err := fetcher.Get(ce.Fetcher).FetchVersion(context.Background(), ce.FetcherConfig)
-	ver = strings.TrimPrefix(ver, "v")
+	attempt := &database.CheckAttempt{
+		CatalogName: ce.Name,
+		CatalogTag:  ce.Tag,
+		Started:     time.Now().UTC(),
+	}
+
+	raw, ver, vertime, err := fetchVersion(ctx, ce, cm.CurrentVersion)
+	if err != nil && appCtx.Err() != nil {
+		// The check was aborted by the shutdown, this is not an error
+		// of the entry and must not be stored
+		logger.Debug("Check aborted by shutdown")
+		return nil
+	}
 	vertime = vertime.Truncate(time.Second).UTC()
 
 	logger = logger.WithFields(log.Fields{
@@ -64,78 +228,354 @@ func checkForUpdates(ce *database.CatalogEntry) error {
 	})
 
 	var (
-		compareErr   error
-		shouldUpdate = true
+		blockReason string
+		compareErr  error
+		logEntry    *database.LogEntry
 	)
-	if ce.VersionConstraint != nil {
-		shouldUpdate, compareErr = ce.VersionConstraint.ShouldApply(cm.CurrentVersion, ver)
+	if ce.VersionConstraint != nil && err == nil {
+		reason, evalErr := ce.VersionConstraint.Evaluate(cm.CurrentVersion, ver)
+		blockReason, compareErr = string(reason), evalErr
 	}
 
+	attempt.FetchedVersion = raw
+	attempt.SelectedVersion = ver
+
 	switch {
 	case err != nil:
 		logger.WithError(err).Error("Fetcher caused error, error is stored in entry")
 		cm.Error = err.Error()
+		attempt.Outcome = database.CheckOutcomeError
 
 	case compareErr != nil:
 		logger.WithError(compareErr).Error("Version compare caused error, error is stored in entry")
 		cm.Error = compareErr.Error()
+		attempt.Outcome = database.CheckOutcomeError
 
-	case cm.CurrentVersion != ver && !shouldUpdate:
-		logger.Info("Version-updated prevented by constraints")
+	case cm.CurrentVersion != ver && blockReason != "":
+		logger.WithField("reason", blockReason).Info("Version-updated prevented by constraints")
+		cm.BlockedReason = blockReason
+		cm.BlockedVersion = ver
 		cm.Error = ""
+		attempt.Outcome = database.CheckOutcomeBlocked
 
-	case cm.CurrentVersion != ver && shouldUpdate:
+	case cm.CurrentVersion != ver:
 		logger.Info("Entry had version update")
+		attempt.Outcome = database.CheckOutcomeUpdated
 
-		if err = storage.Logs.Add(&database.LogEntry{
+		logEntry = &database.LogEntry{
 			CatalogName: ce.Name,
 			CatalogTag:  ce.Tag,
 			Timestamp:   time.Now().Truncate(time.Second).UTC(),
 			VersionTo:   ver,
 			VersionFrom: cm.CurrentVersion,
-		}); err != nil {
-			return fmt.Errorf("adding log entry: %w", err)
 		}
 
-		cm.VersionTime = new(vertime)
+		cm.BlockedReason = ""
+		cm.BlockedVersion = ""
 		cm.CurrentVersion = ver
 		cm.Error = ""
-
-	case cm.CurrentVersion == ver:
-		logger.Debug("Version did not change")
-		cm.Error = ""
+		cm.VersionTime = new(vertime)
 
 	default:
+		logger.Debug("Version did not change")
+		cm.BlockedReason = ""
+		cm.BlockedVersion = ""
 		cm.Error = ""
+		attempt.Outcome = database.CheckOutcomeUnchanged
 	}
 
+	return storeCheckResult(cm, attempt, logEntry)
+}
+
+// storeCheckResult updates the failure tracking of the catalog meta
+// and stores meta, check attempt and log entry (if the version was
+// updated) in one transaction
+func storeCheckResult(cm *database.CatalogMeta, attempt *database.CheckAttempt, logEntry *database.LogEntry) error {
+	attempt.Error = cm.Error
+	attempt.Finished = time.Now().UTC()
+	attempt.DurationMS = attempt.Finished.Sub(attempt.Started).Milliseconds()
+
 	cm.LastChecked = new(time.Now().Truncate(time.Second).UTC())
-	if err = storage.Catalog.PutMeta(cm); err != nil {
-		return fmt.Errorf("updating meta entry: %w", err)
+
+	if cm.Error == "" {
+		cm.ConsecutiveFailures = 0
+		cm.FirstFailure = nil
+	} else {
+		cm.ConsecutiveFailures++
+		if cm.FirstFailure == nil {
+			cm.FirstFailure = cm.LastChecked
+		}
+	}
+
+	// Log entry and meta must be written together: if the meta is not
+	// updated the next check would log the same update again
+	if err := storage.Transaction(func(tx *database.Client) error {
+		if logEntry != nil {
+			if err := tx.Logs.Add(logEntry); err != nil {
+				return fmt.Errorf("adding log entry: %w", err)
+			}
+		}
+
+		if err := tx.Checks.Add(attempt); err != nil {
+			return fmt.Errorf("adding check attempt: %w", err)
+		}
+
+		if err := tx.Catalog.PutMeta(cm); err != nil {
+			return fmt.Errorf("updating meta entry: %w", err)
+		}
+
+		return nil
+	}); err != nil {
+		return fmt.Errorf("storing check result: %w", err)
 	}
 
 	return nil
 }
 
+// fetchVersion retrieves the version to compare against the current
+// version: for fetchers able to list all versions the best candidate
+// is chosen by the version constraint (falling back to the highest
+// candidate inside the range to report why it is blocked), otherwise
+// the fetcher decides which version is the latest one. Next to the
+// transformed version the raw version reported by the fetcher is
+// returned.
+func fetchVersion(ctx context.Context, ce *database.CatalogEntry, currentVersion string) (raw, ver string, vertime time.Time, err error) {
+	var (
+		f         = fetcher.Get(ce.Fetcher)
+		transform = ce.VersionTransform()
+	)
+
+	vf, ok := f.(fetcher.VersionsFetcher)
+	if !ok || ce.VersionConstraint == nil {
+		if raw, vertime, err = f.FetchVersion(ctx, ce.FetcherConfig); err != nil {
+			return "", "", time.Time{}, fmt.Errorf("fetching version: %w", err)
+		}
+
+		if ver, err = transform.Apply(raw); err != nil {
+			return raw, "", time.Time{}, fmt.Errorf("transforming version: %w", err)
+		}
+
+		return raw, ver, vertime, nil
+	}
+
+	cands, err := vf.FetchVersions(ctx, ce.FetcherConfig)
+	if err != nil {
+		return "", "", time.Time{}, fmt.Errorf("fetching versions: %w", err)
+	}
+
+	var (
+		vers     = make([]string, 0, len(cands))
+		verCands = make(map[string]fetcher.VersionCandidate, len(cands))
+	)
+	for _, cand := range cands {
+		if cand.Prerelease && !ce.VersionConstraint.AllowPrerelease {
+			// Marked as pre-release by the source even if the version
+			// itself does not look like one
+			continue
+		}
+
+		candVer, err := transform.Apply(cand.Version)
+		if err != nil {
+			// Transforms are allowed to reject unwanted candidates
+			log.WithField("entry", ce.Key()).WithError(err).Tracef("Ignoring candidate %q", cand.Version)
+			continue
+		}
+
+		vers = append(vers, candVer)
+		verCands[candVer] = cand
+	}
+
+	if ver, err = ce.VersionConstraint.Select(currentVersion, vers); err != nil {
+		return "", "", time.Time{}, fmt.Errorf("selecting version: %w", err)
+	}
+
+	if ver == "" {
+		// No candidate should be applied, continue with the highest one
+		// inside the range to let the constraint explain why it was not
+		// applied
+		ver = ce.VersionConstraint.Highest(vers)
+	}
+
+	switch {
+	case ver != "":
+		return verCands[ver].Version, ver, verCands[ver].Time, nil
+
+	case currentVersion != "":
+		// No candidate is valid, keep the current version
+		return "", currentVersion, time.Time{}, nil
+
+	default:
+		return "", "", time.Time{}, fetcher.ErrNoVersionFound
+	}
+}
+
+// backoffCheckTime delays the next check of entries failing
+// repeatedly: starting with the second failure the delay between the
+// regular checks of the entry is doubled for every failure until
+// reaching --backoff-max
+func backoffCheckTime(ce *database.CatalogEntry, cm *database.CatalogMeta, next time.Time) time.Time {
+	if cm.ConsecutiveFailures < 2 || cm.LastChecked == nil { //revive:disable-line:add-constant // First failure is checked regularly
+		return next
+	}
+
+	delay := scheduleInterval(ce, *cm.LastChecked)
+	for range cm.ConsecutiveFailures - 1 {
+		delay *= 2
+		if delay >= cfg.BackoffMax {
+			delay = cfg.BackoffMax
+			break
+		}
+	}
+
+	if backoff := cm.LastChecked.Add(delay); backoff.After(next) {
+		return backoff.Truncate(time.Second)
+	}
+
+	return next
+}
+
+// checkInterval returns the interval the catalog entry should be
+// checked in: the entry's own interval takes precedence over the one
+// of the config file which takes precedence over the CLI flag
+func checkInterval(ce *database.CatalogEntry) time.Duration {
+	switch {
+	case ce.CheckInterval > 0:
+		return ce.CheckInterval
+	case configFile.CheckInterval > 0:
+		return configFile.CheckInterval
+	default:
+		return cfg.CheckDistribution
+	}
+}
+
+// scheduleInterval returns the delay between two regular checks of the
+// catalog entry following the given time: for entries having a check
+// schedule this is the distance of the next two scheduled checks
+func scheduleInterval(ce *database.CatalogEntry, after time.Time) time.Duration {
+	if ce.CheckSchedule == "" {
+		return checkInterval(ce)
+	}
+
+	sched, err := cron.ParseStandard(ce.CheckSchedule)
+	if err != nil {
+		// Should not happen as the catalog is validated on load
+		return checkInterval(ce)
+	}
+
+	next := sched.Next(after)
+	return sched.Next(next).Sub(next)
+}
+
 func nextCheckTime(ce *database.CatalogEntry, lastCheck *time.Time) time.Time {
 	if lastCheck == nil {
 		// Has never been checked, check ASAP
 		return time.Now()
 	}
 
-	var jitter int64
+	if ce.CheckSchedule != "" {
+		sched, err := cron.ParseStandard(ce.CheckSchedule)
+		if err == nil {
+			return sched.Next(*lastCheck)
+		}
+
+		// Should not happen as the catalog is validated on load
+		log.WithField("entry", ce.Key()).WithError(err).Error("Invalid check schedule, falling back to interval")
+	}
+
+	var (
+		interval = checkInterval(ce)
+		jitter   int64
+	)
 	//#nosec:G401 // Used to derive a static jitter checksum, not cryptographically
 	for i, c := range md5.Sum([]byte(ce.Key())) {
 		jitter += int64(c) * int64(math.Pow(10, float64(i))) //revive:disable-line:add-constant // No need for constant here
 	}
 
 	next := lastCheck.
-		Truncate(cfg.CheckDistribution).
-		Add(time.Duration(jitter) % cfg.CheckDistribution)
+		Truncate(interval).
+		Add(time.Duration(jitter) % interval)
 
 	if next.Before(lastCheck.Add(schedulerInterval)) {
-		next = next.Add(cfg.CheckDistribution)
+		next = next.Add(interval)
 	}
 
 	return next.Truncate(time.Second)
 }
+
+// lockEntry locks the catalog entry and returns the function to
+// unlock it again. When running multiple replicas forced checks and
+// webhooks are executed by the replica receiving the request, so the
+// entry is additionally locked using a lease in the shared database.
+func lockEntry(ctx context.Context, ce *database.CatalogEntry) (func(), error) {
+	l, _ := entryLocks.LoadOrStore(ce.Key(), &sync.Mutex{})
+	mu := l.(*sync.Mutex) //nolint:forcetypeassert // Map only contains *sync.Mutex
+	mu.Lock()
+
+	if cfg.LeaderLeaseTTL <= 0 {
+		// Election is disabled, there are no other replicas
+		return mu.Unlock, nil
+	}
+
+	// The check is aborted after the check timeout, the lease must
+	// outlive it to cover storing the result
+	leaseName := entryLeasePrefix + ce.Key()
+	leaseTTL := cfg.CheckTimeout + cfg.LeaderLeaseTTL
+
+	for {
+		acquired, err := storage.Leases.Acquire(leaseName, instanceID, leaseTTL)
+		if err != nil {
+			mu.Unlock()
+			return nil, fmt.Errorf("acquiring entry lease: %w", err)
+		}
+
+		if acquired {
+			break
+		}
+
+		select {
+		case <-ctx.Done():
+			mu.Unlock()
+			return nil, fmt.Errorf("waiting for entry lease: %w", ctx.Err())
+
+		case <-time.After(entryLeaseRetryInterval):
+			// Another replica is checking the entry, try again
+		}
+	}
+
+	return func() {
+		helpers.LogIfErr(storage.Leases.Release(leaseName, instanceID), "releasing entry lease")
+		mu.Unlock()
+	}, nil
+}
+
+// acquire blocks until a slot for the given fetcher and a slot of the
+// total checks are available and returns a function to release both
+// slots again
+func (c *checkLimiter) acquire(fetcherName string) func() {
+	// The fetcher slot is taken first to not block total slots while
+	// waiting for a busy fetcher
+	releaseFetcher := c.acquireFetcher(fetcherName)
+
+	c.total <- struct{}{}
+	return func() {
+		<-c.total
+		releaseFetcher()
+	}
+}
+
+func (c *checkLimiter) acquireFetcher(fetcherName string) func() {
+	if c.perFetcher <= 0 {
+		return func() {}
+	}
+
+	c.lock.Lock()
+	slot, ok := c.slots[fetcherName]
+	if !ok {
+		slot = make(chan struct{}, c.perFetcher)
+		c.slots[fetcherName] = slot
+	}
+	c.lock.Unlock()
+
+	slot <- struct{}{}
+	return func() { <-slot }
+}
@@ -0,0 +1,387 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Luzifer/go_helpers/fieldcollection"
+
+	"github.com/Luzifer/go-latestver/internal/config"
+	"github.com/Luzifer/go-latestver/internal/database"
+	versioning "github.com/Luzifer/go-latestver/internal/version"
+)
+
+const schedulerTestHelmIndex = `apiVersion: v1
+entries:
+  app:
+    - { apiVersion: v2, name: app, version: 1.28.3, created: "2024-01-01T00:00:00Z" }
+    - { apiVersion: v2, name: app, version: 1.28.5, created: "2024-02-01T00:00:00Z" }
+    - { apiVersion: v2, name: app, version: 1.29.0-rc.1, created: "2024-02-15T00:00:00Z" }
+    - { apiVersion: v2, name: app, version: 1.30.0, created: "2024-03-01T00:00:00Z" }
+  prefixed:
+    - { apiVersion: v2, name: prefixed, version: v1.28.3, created: "2024-01-01T00:00:00Z" }
+    - { apiVersion: v2, name: prefixed, version: v1.28.5, created: "2024-02-01T00:00:00Z" }
+`
+
+func TestNextCheckTime(t *testing.T) {
+	cfg.CheckDistribution = time.Hour
+	configFile = config.New()
+
+	lastCheck := time.Date(2024, 1, 15, 10, 17, 0, 0, time.UTC)
+
+	if next := nextCheckTime(&database.CatalogEntry{Name: "app"}, nil); time.Since(next) > time.Minute {
+		t.Errorf("never checked: expected check now, got %s", next)
+	}
+
+	for _, tc := range []struct {
+		name           string
+		ce             database.CatalogEntry
+		globalInterval time.Duration
+		interval       time.Duration
+	}{
+		{"check distribution", database.CatalogEntry{Name: "app", Tag: "stable"}, 0, time.Hour},
+		{"config file interval", database.CatalogEntry{Name: "app", Tag: "stable"}, 6 * time.Hour, 6 * time.Hour},
+		{"entry interval", database.CatalogEntry{Name: "app", Tag: "stable", CheckInterval: 15 * time.Minute}, 6 * time.Hour, 15 * time.Minute},
+	} {
+		configFile.CheckInterval = tc.globalInterval
+
+		next := nextCheckTime(&tc.ce, &lastCheck)
+		if next.Before(lastCheck.Add(schedulerInterval)) || !next.Before(lastCheck.Add(tc.interval+schedulerInterval)) {
+			t.Errorf("%s: expected next check within %s after %s, got %s", tc.name, tc.interval, lastCheck, next)
+		}
+
+		// The jitter is static, following checks are one interval apart
+		if following := nextCheckTime(&tc.ce, &next); following.Sub(next) != tc.interval {
+			t.Errorf("%s: expected following check %s after %s, got %s", tc.name, tc.interval, next, following)
+		}
+	}
+
+	configFile.CheckInterval = 0
+
+	ce := database.CatalogEntry{Name: "app", Tag: "stable", CheckInterval: time.Minute, CheckSchedule: "0 4 * * *"}
+	if next, expect := nextCheckTime(&ce, &lastCheck), time.Date(2024, 1, 16, 4, 0, 0, 0, time.UTC); !next.Equal(expect) {
+		t.Errorf("check schedule: expected %s, got %s", expect, next)
+	}
+}
+
+func TestBackoffCheckTime(t *testing.T) {
+	cfg.BackoffMax = 24 * time.Hour
+	cfg.CheckDistribution = time.Hour
+	configFile = config.New()
+
+	var (
+		lastCheck = time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)
+		next      = lastCheck.Add(time.Hour)
+		hourly    = database.CatalogEntry{Name: "app", Tag: "stable", CheckInterval: time.Hour}
+		daily     = database.CatalogEntry{Name: "app", Tag: "stable", CheckSchedule: "0 4 * * *"}
+	)
+
+	for _, tc := range []struct {
+		name       string
+		ce         database.CatalogEntry
+		failures   int
+		backoffMax time.Duration
+		next       time.Time
+		expect     time.Time
+	}{
+		{"no failure", hourly, 0, 24 * time.Hour, next, next},
+		{"first failure", hourly, 1, 24 * time.Hour, next, next},
+		{"second failure", hourly, 2, 24 * time.Hour, next, lastCheck.Add(2 * time.Hour)},
+		{"third failure", hourly, 3, 24 * time.Hour, next, lastCheck.Add(4 * time.Hour)},
+		{"backoff ceiling", hourly, 10, 24 * time.Hour, next, lastCheck.Add(24 * time.Hour)},
+		{"lowered ceiling", hourly, 10, 3 * time.Hour, next, lastCheck.Add(3 * time.Hour)},
+		{"backoff disabled", hourly, 10, 0, next, next},
+		{"regular check later", hourly, 2, 24 * time.Hour, lastCheck.Add(3 * time.Hour), lastCheck.Add(3 * time.Hour)},
+		{"check distribution", database.CatalogEntry{Name: "app", Tag: "stable"}, 2, 24 * time.Hour, next, lastCheck.Add(2 * time.Hour)},
+		{"check schedule", daily, 2, 72 * time.Hour, next, lastCheck.Add(48 * time.Hour)},
+		{"check schedule ceiling", daily, 3, 72 * time.Hour, next, lastCheck.Add(72 * time.Hour)},
+	} {
+		cfg.BackoffMax = tc.backoffMax
+
+		cm := &database.CatalogMeta{ConsecutiveFailures: tc.failures, LastChecked: &lastCheck}
+		if got := backoffCheckTime(&tc.ce, cm, tc.next); !got.Equal(tc.expect) {
+			t.Errorf("%s: expected %s, got %s", tc.name, tc.expect, got)
+		}
+	}
+}
+
+func TestBackoffResetOnSuccess(t *testing.T) {
+	var err error
+	if storage, err = database.NewClient("sqlite3", "file:backoffreset?mode=memory&cache=shared"); err != nil {
+		t.Fatalf("unable to create database client: %s", err)
+	}
+	t.Cleanup(func() { _ = storage.Close() })
+
+	cfg.BackoffMax = 24 * time.Hour
+	configFile = config.New()
+
+	var (
+		ce        = &database.CatalogEntry{Name: "app", Tag: "stable", CheckInterval: time.Hour}
+		lastCheck = time.Now().Add(-time.Hour).Truncate(time.Second).UTC()
+		cm        = &database.CatalogMeta{CatalogName: ce.Name, CatalogTag: ce.Tag, LastChecked: &lastCheck}
+	)
+
+	for _, tc := range []struct {
+		name     string
+		err      string
+		failures int
+	}{
+		{"first failure", "fetch failed", 1},
+		{"second failure", "fetch failed", 2},
+		{"success", "", 0},
+	} {
+		cm.Error = tc.err
+		if err = storeCheckResult(cm, &database.CheckAttempt{CatalogName: ce.Name, CatalogTag: ce.Tag}, nil); err != nil {
+			t.Fatalf("%s: storing check result: %s", tc.name, err)
+		}
+
+		if cm, err = storage.Catalog.GetMeta(ce); err != nil {
+			t.Fatalf("%s: getting catalog meta: %s", tc.name, err)
+		}
+
+		if cm.ConsecutiveFailures != tc.failures {
+			t.Errorf("%s: expected %d failures, got %d", tc.name, tc.failures, cm.ConsecutiveFailures)
+		}
+
+		if (cm.FirstFailure != nil) != (tc.failures > 0) {
+			t.Errorf("%s: unexpected first failure %v", tc.name, cm.FirstFailure)
+		}
+	}
+
+	next := nextCheckTime(ce, cm.LastChecked)
+	if got := backoffCheckTime(ce, cm, next); !got.Equal(next) {
+		t.Errorf("expected regular check at %s after success, got %s", next, got)
+	}
+}
+
+func TestCheckLimiter(t *testing.T) {
+	var (
+		limiter  = newCheckLimiter(2, 1)
+		acquired = make(chan string, 3)
+		releases = make(chan func(), 3)
+	)
+
+	acquire := func(fetcherName string) {
+		go func() {
+			releases <- limiter.acquire(fetcherName)
+			acquired <- fetcherName
+		}()
+	}
+
+	expectAcquired := func(expect string) {
+		t.Helper()
+		select {
+		case got := <-acquired:
+			if got != expect {
+				t.Errorf("expected slot for %q, got %q", expect, got)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("expected slot for %q to be acquired", expect)
+		}
+	}
+
+	expectBlocked := func() {
+		t.Helper()
+		select {
+		case got := <-acquired:
+			t.Fatalf("expected no slot to be available, got one for %q", got)
+		case <-time.After(50 * time.Millisecond):
+		}
+	}
+
+	acquire("html")
+	expectAcquired("html")
+
+	// Limited by the fetcher
+	acquire("html")
+	expectBlocked()
+
+	acquire("github_release")
+	expectAcquired("github_release")
+
+	// Limited by the total
+	acquire("regex")
+	expectBlocked()
+
+	// Releasing the first html check frees the total and the fetcher
+	// slot, which of both waiting checks gets it is not defined
+	(<-releases)()
+	select {
+	case <-acquired:
+	case <-time.After(time.Second):
+		t.Fatal("expected a waiting check to get the released slot")
+	}
+	expectBlocked()
+}
+
+func TestCheckForUpdatesNotDue(t *testing.T) {
+	var err error
+	if storage, err = database.NewClient("sqlite3", "file:checknotdue?mode=memory&cache=shared"); err != nil {
+		t.Fatalf("unable to create database client: %s", err)
+	}
+	t.Cleanup(func() { _ = storage.Close() })
+
+	cfg.LeaderLeaseTTL = 30 * time.Second
+	configFile = config.New()
+
+	var (
+		ce          = &database.CatalogEntry{Name: "app", Tag: "stable", CheckInterval: time.Hour}
+		lastChecked = time.Now().Truncate(time.Second).UTC()
+	)
+
+	if err = storage.Catalog.PutMeta(&database.CatalogMeta{CatalogName: ce.Name, CatalogTag: ce.Tag, LastChecked: &lastChecked}); err != nil {
+		t.Fatalf("storing catalog meta: %s", err)
+	}
+
+	// Entries not due must not wait for the entry lock
+	unlock, err := lockEntry(t.Context(), ce)
+	if err != nil {
+		t.Fatalf("locking entry: %s", err)
+	}
+	defer unlock()
+
+	done := make(chan error, 1)
+	go func() { done <- checkForUpdates(t.Context(), ce) }()
+
+	select {
+	case err = <-done:
+		if err != nil {
+			t.Errorf("checking entry: %s", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("check of entry not due waited for the entry lock")
+	}
+}
+
+func TestExecuteCheckRange(t *testing.T) {
+	var err error
+	if storage, err = database.NewClient("sqlite3", "file:executecheckrange?mode=memory&cache=shared"); err != nil {
+		t.Fatalf("unable to create database client: %s", err)
+	}
+	t.Cleanup(func() { _ = storage.Close() })
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Write([]byte(schedulerTestHelmIndex)) //nolint:errcheck,gosec // test server
+	}))
+	t.Cleanup(srv.Close)
+
+	for _, tc := range []struct {
+		name           string
+		chart          string
+		constraint     versioning.Constraint
+		current        string
+		outcome        database.CheckOutcome
+		version        string
+		blockedVersion string
+		blockedReason  string
+		fetched        string
+		selected       string
+	}{
+		{
+			name:       "newest in range",
+			constraint: versioning.Constraint{Type: "semver", Range: "~1.28"},
+			current:    "1.28.5",
+			outcome:    database.CheckOutcomeUnchanged,
+			version:    "1.28.5",
+			fetched:    "1.28.5",
+			selected:   "1.28.5",
+		},
+		{
+			name:       "update in range",
+			constraint: versioning.Constraint{Type: "semver", Range: "~1.28"},
+			current:    "1.28.3",
+			outcome:    database.CheckOutcomeUpdated,
+			version:    "1.28.5",
+			fetched:    "1.28.5",
+			selected:   "1.28.5",
+		},
+		{
+			name:       "transformed version",
+			chart:      "prefixed",
+			constraint: versioning.Constraint{Type: "semver", Range: "~1.28"},
+			current:    "1.28.3",
+			outcome:    database.CheckOutcomeUpdated,
+			version:    "1.28.5",
+			fetched:    "v1.28.5",
+			selected:   "1.28.5",
+		},
+		{
+			name:       "initial version in range",
+			constraint: versioning.Constraint{Type: "semver", Range: "~1.28"},
+			outcome:    database.CheckOutcomeUpdated,
+			version:    "1.28.5",
+			fetched:    "1.28.5",
+			selected:   "1.28.5",
+		},
+		{
+			name:           "downgrade in range",
+			constraint:     versioning.Constraint{Type: "semver", Range: "~1.28"},
+			current:        "1.28.9",
+			outcome:        database.CheckOutcomeBlocked,
+			version:        "1.28.9",
+			blockedVersion: "1.28.5",
+			blockedReason:  string(versioning.BlockReasonDowngrade),
+			fetched:        "1.28.5",
+			selected:       "1.28.5",
+		},
+		{
+			name:       "no version in range",
+			constraint: versioning.Constraint{Type: "semver", Range: "~1.27"},
+			current:    "1.27.2",
+			outcome:    database.CheckOutcomeUnchanged,
+			version:    "1.27.2",
+			selected:   "1.27.2",
+		},
+		{
+			name:           "pre-release in range",
+			constraint:     versioning.Constraint{Type: "semver", Range: "<1.30.0"},
+			current:        "1.28.5",
+			outcome:        database.CheckOutcomeBlocked,
+			version:        "1.28.5",
+			blockedVersion: "1.29.0-rc.1",
+			blockedReason:  string(versioning.BlockReasonPrerelease),
+			fetched:        "1.29.0-rc.1",
+			selected:       "1.29.0-rc.1",
+		},
+	} {
+		if tc.chart == "" {
+			tc.chart = "app"
+		}
+
+		ce := &database.CatalogEntry{
+			Name:              "app",
+			Tag:               tc.name,
+			Fetcher:           "helm",
+			FetcherConfig:     fieldcollection.FromData(map[string]any{"repo": srv.URL, "chart": tc.chart}),
+			VersionConstraint: &tc.constraint,
+		}
+
+		cm := &database.CatalogMeta{CatalogName: ce.Name, CatalogTag: ce.Tag, CurrentVersion: tc.current}
+		if err = executeCheck(t.Context(), ce, cm); err != nil {
+			t.Errorf("%s: executing check: %s", tc.name, err)
+			continue
+		}
+
+		if cm, err = storage.Catalog.GetMeta(ce); err != nil {
+			t.Fatalf("%s: getting catalog meta: %s", tc.name, err)
+		}
+
+		if cm.CurrentVersion != tc.version || cm.BlockedVersion != tc.blockedVersion || cm.BlockedReason != tc.blockedReason {
+			t.Errorf("%s: expected version %q (blocked %q / %q), got %q (blocked %q / %q)", tc.name,
+				tc.version, tc.blockedVersion, tc.blockedReason, cm.CurrentVersion, cm.BlockedVersion, cm.BlockedReason)
+		}
+
+		attempts, err := storage.Checks.ListForCatalogEntry(ce, 1, 0)
+		if err != nil {
+			t.Fatalf("%s: listing check attempts: %s", tc.name, err)
+		}
+
+		if len(attempts) != 1 || attempts[0].Outcome != tc.outcome ||
+			attempts[0].FetchedVersion != tc.fetched || attempts[0].SelectedVersion != tc.selected {
+			t.Errorf("%s: expected one check attempt with outcome %q fetching %q selecting %q, got %+v",
+				tc.name, tc.outcome, tc.fetched, tc.selected, attempts)
+		}
+	}
+}